@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// CompressionInterceptor возвращает интерсептор, который сжимает ответы gzip для клиентов,
+// объявивших поддержку gzip в grpc-accept-encoding. Ответы остальным клиентам не сжимаются.
+// Импорт пакета gzip регистрирует компрессор, поэтому сжатые gzip запросы принимаются и без интерсептора
+func CompressionInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if supported, err := grpc.ClientSupportedCompressors(ctx); err == nil && slices.Contains(supported, gzip.Name) {
+			// Ошибка означает, что клиент не принимает gzip; ответ тогда отправляется без сжатия
+			_ = grpc.SetSendCompressor(ctx, gzip.Name)
+		}
+		return handler(ctx, req)
+	}
+}
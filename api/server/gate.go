@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/watchlist-kata/protos/subscription"
+)
+
+// DisabledMethodsInterceptor возвращает интерсептор, который отклоняет вызовы отключенных методов сервиса подписок
+// с кодом Unimplemented. Методы задаются короткими именами (например, Subscribe); неизвестное имя считается ошибкой конфигурации
+func DisabledMethodsInterceptor(methods []string) (grpc.UnaryServerInterceptor, error) {
+	known := make(map[string]bool, len(pb.SubscriptionService_ServiceDesc.Methods))
+	for _, method := range pb.SubscriptionService_ServiceDesc.Methods {
+		known[method.MethodName] = true
+	}
+
+	disabled := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		if !known[method] {
+			return nil, fmt.Errorf("unknown subscription service method: %q", method)
+		}
+		disabled["/"+pb.SubscriptionService_ServiceDesc.ServiceName+"/"+method] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if disabled[info.FullMethod] {
+			return nil, status.Errorf(codes.Unimplemented, "method %s is disabled", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}, nil
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"math"
+
+	"github.com/watchlist-kata/subscription/internal/service"
+)
+
+// toUserID проверяет ID пользователя из запроса и преобразует его в доменный тип.
+// Неположительные значения и значения, не помещающиеся в uint, отклоняются с кодом InvalidArgument
+func toUserID(field string, id int64) (uint, error) {
+	if id <= 0 {
+		return 0, service.InvalidArgumentError("invalid "+field, field, "must be a positive ID")
+	}
+	if uint64(id) > math.MaxUint {
+		return 0, service.InvalidArgumentError("invalid "+field, field, "is out of range")
+	}
+	return uint(id), nil
+}
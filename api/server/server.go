@@ -2,16 +2,49 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/watchlist-kata/protos/subscription"
+	"github.com/watchlist-kata/subscription/internal/repository"
 	"github.com/watchlist-kata/subscription/internal/service"
 )
 
+const (
+	// feedTruncatedHeader — заголовок ответа, которым помечается обрезанная лента
+	feedTruncatedHeader = "x-feed-truncated"
+	// feedForceRefreshHeader — заголовок запроса, которым клиент требует построить ленту заново в обход кэша
+	feedForceRefreshHeader = "x-feed-force-refresh"
+	// feedIncludeSelfHeader — заголовок запроса, которым клиент требует добавить в ленту собственную активность
+	feedIncludeSelfHeader = "x-feed-include-self"
+	// feedRawHeader — заголовок запроса, которым клиент требует ленту вотчлистов без обогащения
+	feedRawHeader = "x-feed-raw"
+	// feedShardHeader — заголовок запроса с частью подписок в формате K/N: лента строится только по пользователям с user_id % N == K
+	feedShardHeader = "x-feed-shard"
+	// feedIncompleteHeader — заголовок ответа, которым помечается лента, собранная не полностью из-за дедлайна запроса
+	feedIncompleteHeader = "x-feed-incomplete"
+	// feedSortByHeader — заголовок запроса с порядком ленты отзывов: recent (по умолчанию) или rating
+	feedSortByHeader = "x-feed-sort-by"
+	// feedUnseenHeader — заголовок запроса, которым клиент требует только элементы, появившиеся после отметки просмотра ленты
+	feedUnseenHeader = "x-feed-unseen"
+	// feedMaxContentLengthHeader — заголовок запроса с максимальной длиной текста отзывов в символах (по умолчанию без обрезки)
+	feedMaxContentLengthHeader = "x-feed-max-content-length"
+	// feedMinRatingHeader — заголовок запроса с минимальной оценкой отзывов в ленте (по умолчанию все отзывы)
+	feedMinRatingHeader = "x-feed-min-rating"
+	// subscriptionIDHeader — заголовок ответа с ID созданной подписки
+	subscriptionIDHeader = "x-subscription-id"
+	// subscribedSinceHeader — заголовок ответа с датой создания подписки в формате RFC3339
+	subscribedSinceHeader = "x-subscribed-since"
+)
+
 // GrpcSubscriptionServer реализует gRPC-сервис подписок
 type GrpcSubscriptionServer struct {
 	pb.UnimplementedSubscriptionServiceServer
@@ -25,32 +58,67 @@ func NewGrpcSubscriptionServer(subscriptionService service.SubscriptionService)
 	}
 }
 
-// Subscribe обрабатывает gRPC-запрос на подписку
+// Subscribe обрабатывает gRPC-запрос на подписку.
+// ID и дата создания подписки передаются в заголовках ответа x-subscription-id и x-subscribed-since.
+// Коды InvalidArgument, NotFound, AlreadyExists, Unavailable и Canceled возвращаются клиенту без изменений,
+// остальные ошибки возвращаются как Internal
 func (s *GrpcSubscriptionServer) Subscribe(ctx context.Context, req *pb.SubscribeRequest) (*pb.SubscribeResponse, error) {
-	if req.SubscriberId == req.SubscribeToId {
-		return nil, status.Errorf(codes.InvalidArgument, "cannot subscribe to yourself")
+	subscriberID, err := toUserID("subscriber_id", req.SubscriberId)
+	if err != nil {
+		return nil, err
+	}
+	subscribeToID, err := toUserID("subscribe_to_id", req.SubscribeToId)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.subscriptionService.Subscribe(ctx, uint(req.SubscriberId), uint(req.SubscribeToId))
+	created, err := s.subscriptionService.Subscribe(ctx, subscriberID, subscribeToID)
 	if err != nil {
 		// Обработка ошибок
-		if strings.Contains(err.Error(), "already exists") {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.Unavailable, codes.Canceled:
+			return nil, err
+		case codes.NotFound:
+			return nil, status.Errorf(codes.NotFound, "user to subscribe to does not exist")
+		case codes.AlreadyExists:
 			return nil, status.Errorf(codes.AlreadyExists, "subscription already exists")
 		}
 		log.Printf("Failed to subscribe: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to process subscription")
 	}
 
+	// Нулевой ID означает, что подписка не создавалась (подписка на самого себя при политике ignore)
+	if created.ID != 0 {
+		header := metadata.Pairs(
+			subscriptionIDHeader, strconv.FormatUint(uint64(created.ID), 10),
+			subscribedSinceHeader, created.CreatedAt.UTC().Format(time.RFC3339),
+		)
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			log.Printf("Failed to set subscription headers: %v", err)
+		}
+	}
+
 	return &pb.SubscribeResponse{Success: true}, nil
 }
 
-// Unsubscribe обрабатывает gRPC-запрос на отписку
+// Unsubscribe обрабатывает gRPC-запрос на отписку.
+// Коды NotFound и Canceled возвращаются клиенту без изменений, остальные ошибки возвращаются как Internal
 func (s *GrpcSubscriptionServer) Unsubscribe(ctx context.Context, req *pb.UnsubscribeRequest) (*pb.UnsubscribeResponse, error) {
-	err := s.subscriptionService.Unsubscribe(ctx, uint(req.SubscriberId), uint(req.UnsubscribeFromId))
+	subscriberID, err := toUserID("subscriber_id", req.SubscriberId)
 	if err != nil {
-		// Обработка ошибок
-		if strings.Contains(err.Error(), "does not exist") {
-			return nil, status.Errorf(codes.NotFound, "subscription does not exist")
+		return nil, err
+	}
+	unsubscribeFromID, err := toUserID("unsubscribe_from_id", req.UnsubscribeFromId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.subscriptionService.Unsubscribe(ctx, subscriberID, unsubscribeFromID)
+	if err != nil {
+		// Обработка ошибок по коду статуса: текст ошибки базы данных тоже может содержать "does not exist"
+		switch status.Code(err) {
+		case codes.NotFound, codes.Canceled:
+			return nil, err
 		}
 		log.Printf("Failed to unsubscribe: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to process unsubscription")
@@ -61,7 +129,12 @@ func (s *GrpcSubscriptionServer) Unsubscribe(ctx context.Context, req *pb.Unsubs
 
 // GetSubscriptions обрабатывает gRPC-запрос на получение списка подписок пользователя
 func (s *GrpcSubscriptionServer) GetSubscriptions(ctx context.Context, req *pb.GetSubscriptionsRequest) (*pb.GetSubscriptionsResponse, error) {
-	subscriptions, err := s.subscriptionService.GetSubscriptions(ctx, uint(req.UserId))
+	userID, err := toUserID("user_id", req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.subscriptionService.GetSubscriptions(ctx, userID)
 	if err != nil {
 		log.Printf("Failed to get subscriptions: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to get subscriptions")
@@ -78,7 +151,12 @@ func (s *GrpcSubscriptionServer) GetSubscriptions(ctx context.Context, req *pb.G
 
 // GetSubscribers обрабатывает gRPC-запрос на получение списка подписчиков пользователя
 func (s *GrpcSubscriptionServer) GetSubscribers(ctx context.Context, req *pb.GetSubscribersRequest) (*pb.GetSubscribersResponse, error) {
-	subscribers, err := s.subscriptionService.GetSubscribers(ctx, uint(req.UserId))
+	userID, err := toUserID("user_id", req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers, err := s.subscriptionService.GetSubscribers(ctx, userID)
 	if err != nil {
 		log.Printf("Failed to get subscribers: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to get subscribers")
@@ -93,35 +171,195 @@ func (s *GrpcSubscriptionServer) GetSubscribers(ctx context.Context, req *pb.Get
 	return &pb.GetSubscribersResponse{SubscriberIds: subscriberIds}, nil
 }
 
-// CheckSubscription обрабатывает gRPC-запрос на проверку подписки
+// CheckSubscription обрабатывает gRPC-запрос на проверку подписки.
+// Если подписка существует, дата ее создания передается в заголовке ответа x-subscribed-since
 func (s *GrpcSubscriptionServer) CheckSubscription(ctx context.Context, req *pb.CheckSubscriptionRequest) (*pb.CheckSubscriptionResponse, error) {
-	isSubscribed, err := s.subscriptionService.IsSubscribed(ctx, uint(req.SubscriberId), uint(req.SubscribeToId))
+	subscriberID, err := toUserID("subscriber_id", req.SubscriberId)
+	if err != nil {
+		return nil, err
+	}
+	subscribeToID, err := toUserID("subscribe_to_id", req.SubscribeToId)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.subscriptionService.GetSubscriptionDetail(ctx, subscriberID, subscribeToID)
 	if err != nil {
 		log.Printf("Failed to check subscription: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to check subscription")
 	}
 
-	return &pb.CheckSubscriptionResponse{IsSubscribed: isSubscribed}, nil
+	if detail.IsSubscribed {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(subscribedSinceHeader, detail.Since.UTC().Format(time.RFC3339))); err != nil {
+			log.Printf("Failed to set subscribed since header: %v", err)
+		}
+	}
+
+	return &pb.CheckSubscriptionResponse{IsSubscribed: detail.IsSubscribed}, nil
 }
 
-// GetWatchlistsBySubscription обрабатывает gRPC-запрос на получение вотчлистов подписок
+// GetWatchlistsBySubscription обрабатывает gRPC-запрос на получение вотчлистов подписок.
+// Если лента обрезана по максимальному числу элементов, в заголовок ответа добавляется x-feed-truncated: true,
+// а если собрана не полностью из-за дедлайна запроса — x-feed-incomplete: true.
+// Заголовок запроса x-feed-force-refresh: true требует построить ленту в обход кэша,
+// x-feed-include-self: true — добавить в ленту вотчлист самого пользователя, а x-feed-raw: true — вернуть
+// только ID пользователей и медиа без обращения к сервисам медиа и пользователей.
+// Заголовок x-feed-shard: K/N ограничивает ленту частью подписок
 func (s *GrpcSubscriptionServer) GetWatchlistsBySubscription(ctx context.Context, req *pb.GetWatchlistsRequest) (*pb.GetWatchlistsResponse, error) {
-	watchlists, err := s.subscriptionService.GetWatchlistsBySubscription(ctx, uint(req.UserId))
+	userID, err := toUserID("user_id", req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	params, err := feedParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params.Raw = feedHeaderFlag(ctx, feedRawHeader)
+	feed, err := s.subscriptionService.GetWatchlistsBySubscription(ctx, userID, params)
 	if err != nil {
 		log.Printf("Failed to get watchlists: %v", err)
+		if hasStatusDetails(err) {
+			return nil, err
+		}
 		return nil, status.Errorf(codes.Internal, "failed to get watchlists")
 	}
 
-	return &pb.GetWatchlistsResponse{Watchlists: watchlists}, nil
+	setFeedHeaders(ctx, feed.Truncated, feed.Incomplete)
+	return &pb.GetWatchlistsResponse{Watchlists: feed.Items}, nil
 }
 
-// GetReviewsBySubscription обрабатывает gRPC-запрос на получение отзывов подписок
+// GetReviewsBySubscription обрабатывает gRPC-запрос на получение отзывов подписок.
+// Если лента обрезана по максимальному числу элементов, в заголовок ответа добавляется x-feed-truncated: true,
+// а если собрана не полностью из-за дедлайна запроса — x-feed-incomplete: true.
+// Заголовок запроса x-feed-force-refresh: true требует построить ленту в обход кэша,
+// x-feed-include-self: true — добавить в ленту отзывы самого пользователя,
+// а x-feed-sort-by: rating — упорядочить отзывы по убыванию оценки вместо новизны.
+// Заголовок x-feed-shard: K/N ограничивает ленту частью подписок
 func (s *GrpcSubscriptionServer) GetReviewsBySubscription(ctx context.Context, req *pb.GetReviewsRequest) (*pb.GetReviewsResponse, error) {
-	reviews, err := s.subscriptionService.GetReviewsBySubscription(ctx, uint(req.UserId))
+	userID, err := toUserID("user_id", req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	sortBy, err := repository.ParseFeedSort(feedHeaderValue(ctx, feedSortByHeader))
+	if err != nil {
+		return nil, service.InvalidArgumentError("invalid feed sort order", feedSortByHeader, "must be recent or rating")
+	}
+
+	params, err := feedParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params.SortBy = sortBy
+	if value := feedHeaderValue(ctx, feedMaxContentLengthHeader); value != "" {
+		maxContentLength, err := strconv.Atoi(value)
+		if err != nil || maxContentLength < 0 {
+			return nil, service.InvalidArgumentError("invalid max content length", feedMaxContentLengthHeader, "must be a non-negative integer")
+		}
+		params.MaxContentLength = maxContentLength
+	}
+	if value := feedHeaderValue(ctx, feedMinRatingHeader); value != "" {
+		minRating, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || minRating < 0 {
+			return nil, service.InvalidArgumentError("invalid min rating", feedMinRatingHeader, "must be a non-negative integer")
+		}
+		params.MinRating = int32(minRating)
+	}
+	feed, err := s.subscriptionService.GetReviewsBySubscription(ctx, userID, params)
 	if err != nil {
 		log.Printf("Failed to get reviews: %v", err)
+		if hasStatusDetails(err) {
+			return nil, err
+		}
 		return nil, status.Errorf(codes.Internal, "failed to get reviews")
 	}
 
-	return &pb.GetReviewsResponse{Reviews: reviews}, nil
+	setFeedHeaders(ctx, feed.Truncated, feed.Incomplete)
+	return &pb.GetReviewsResponse{Reviews: feed.Items}, nil
+}
+
+// hasStatusDetails проверяет, содержит ли статус ошибки детали, которые нужно вернуть клиенту без изменений
+func hasStatusDetails(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && len(st.Details()) > 0
+}
+
+// feedParams формирует параметры ленты из заголовков запроса
+func feedParams(ctx context.Context) (service.FeedParams, error) {
+	shard, err := parseFeedShard(feedHeaderValue(ctx, feedShardHeader))
+	if err != nil {
+		return service.FeedParams{}, service.InvalidArgumentError("invalid feed shard", feedShardHeader, "must be K/N with 0 <= K < N")
+	}
+	return service.FeedParams{
+		ForceRefresh: feedHeaderFlag(ctx, feedForceRefreshHeader),
+		IncludeSelf:  feedHeaderFlag(ctx, feedIncludeSelfHeader),
+		Unseen:       feedHeaderFlag(ctx, feedUnseenHeader),
+		Shard:        shard,
+	}, nil
+}
+
+// parseFeedShard разбирает часть подписок в формате K/N; пустая строка означает ленту без разбиения
+func parseFeedShard(value string) (repository.FeedShard, error) {
+	if value == "" {
+		return repository.FeedShard{}, nil
+	}
+	index, count, ok := strings.Cut(value, "/")
+	if !ok {
+		return repository.FeedShard{}, fmt.Errorf("invalid shard format: %q", value)
+	}
+	var shard repository.FeedShard
+	var err error
+	if shard.Index, err = strconv.Atoi(index); err != nil {
+		return repository.FeedShard{}, err
+	}
+	if shard.Count, err = strconv.Atoi(count); err != nil {
+		return repository.FeedShard{}, err
+	}
+	if shard.Count < 1 {
+		return repository.FeedShard{}, fmt.Errorf("shard count must be positive")
+	}
+	return shard, shard.Validate()
+}
+
+// feedHeaderFlag проверяет, передал ли клиент в заголовке запроса name значение true
+func feedHeaderFlag(ctx context.Context, name string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, value := range md.Get(name) {
+		if flag, err := strconv.ParseBool(value); err == nil && flag {
+			return true
+		}
+	}
+	return false
+}
+
+// feedHeaderValue возвращает первое значение заголовка запроса name
+func feedHeaderValue(ctx context.Context, name string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// setFeedHeaders сообщает клиенту через заголовки ответа, что лента была обрезана или собрана не полностью
+func setFeedHeaders(ctx context.Context, truncated bool, incomplete bool) {
+	md := metadata.MD{}
+	if truncated {
+		md.Set(feedTruncatedHeader, "true")
+	}
+	if incomplete {
+		md.Set(feedIncompleteHeader, "true")
+	}
+	if md.Len() == 0 {
+		return
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		log.Printf("Failed to set feed headers: %v", err)
+	}
 }
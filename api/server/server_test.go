@@ -0,0 +1,106 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/watchlist-kata/protos/subscription"
+
+	"github.com/watchlist-kata/subscription/api/server"
+	"github.com/watchlist-kata/subscription/internal/repository"
+	"github.com/watchlist-kata/subscription/internal/service"
+)
+
+// fakeRepository отвечает на проверку и удаление подписки заданными результатами
+type fakeRepository struct {
+	repository.SubscriptionRepository
+	subscribed     bool
+	checkErr       error
+	unsubscribeErr error
+}
+
+func (r *fakeRepository) IsSubscribed(context.Context, uint, uint) (bool, error) {
+	return r.subscribed, r.checkErr
+}
+
+func (r *fakeRepository) Unsubscribe(context.Context, uint, uint) error {
+	return r.unsubscribeErr
+}
+
+// startServer запускает gRPC-сервер подписок поверх repo на bufconn-листенере и возвращает клиента к нему
+func startServer(t *testing.T, repo repository.SubscriptionRepository) pb.SubscriptionServiceClient {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subscriptionService := service.NewSubscriptionService(repo, logger, nil, service.Options{})
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterSubscriptionServiceServer(grpcServer, server.NewGrpcSubscriptionServer(subscriptionService))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewSubscriptionServiceClient(conn)
+}
+
+func TestUnsubscribeStatusCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     *fakeRepository
+		wantCode codes.Code
+	}{
+		{
+			name:     "subscribed",
+			repo:     &fakeRepository{subscribed: true},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "not subscribed",
+			repo:     &fakeRepository{subscribed: false},
+			wantCode: codes.NotFound,
+		},
+		{
+			// Текст ошибки базы данных содержит "does not exist", но это не отсутствие подписки
+			name:     "check fails",
+			repo:     &fakeRepository{checkErr: errors.New(`relation "subscription" does not exist`)},
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "delete fails",
+			repo:     &fakeRepository{subscribed: true, unsubscribeErr: errors.New("connection reset")},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := startServer(t, tt.repo)
+
+			_, err := client.Unsubscribe(context.Background(), &pb.UnsubscribeRequest{SubscriberId: 1, UnsubscribeFromId: 2})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("got code %s (%v), want %s", got, err, tt.wantCode)
+			}
+		})
+	}
+}
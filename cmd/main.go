@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/watchlist-kata/subscription/internal/config"
+	"github.com/watchlist-kata/subscription/internal/events"
+	"github.com/watchlist-kata/subscription/internal/jobs"
+	"github.com/watchlist-kata/subscription/internal/metrics"
+	"github.com/watchlist-kata/subscription/internal/migrations"
 	"github.com/watchlist-kata/subscription/internal/repository"
 	"github.com/watchlist-kata/subscription/internal/service"
 	"github.com/watchlist-kata/subscription/pkg/logger"
@@ -18,38 +27,213 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Подключение к базе данных и инициализация репозитория и сервиса
-	db, err := utils.SetupDatabase(cfg)
-	if err != nil {
-		log.Fatalf("Failed to setup database: %v", err)
-	}
-
 	// Инициализация логгера
-	logg, err := logger.NewLogger(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.ServiceName, cfg.LogBufferSize)
+	logg, err := logger.NewLogger(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.ServiceName, cfg.LogBufferSize, cfg.LogFormat)
 	if err != nil {
 		log.Fatalf("Failed to create logger: %v", err)
 	}
 	defer func() {
 		if multiHandler, ok := logg.Handler().(*logger.MultiHandler); ok {
-			multiHandler.CloseAll()
+			multiHandler.CloseAll(cfg.LogCloseTimeout)
 		}
 	}()
+	logg.Info("logger initialized", slog.Int("buffer_size", cfg.LogBufferSize), slog.String("format", cfg.LogFormat))
+	if cfg.RawLogBufferSize != cfg.LogBufferSize {
+		logg.Warn("LOG_BUFFER_SIZE is not positive, using default",
+			slog.Int("configured", cfg.RawLogBufferSize), slog.Int("buffer_size", cfg.LogBufferSize))
+	}
 
-	mediaAddr := fmt.Sprintf("%s:%s", cfg.MediaServiceHost, cfg.MediaServicePort)
-	reviewAddr := fmt.Sprintf("%s:%s", cfg.ReviewServiceHost, cfg.ReviewServicePort)
-	watchlistAddr := fmt.Sprintf("%s:%s", cfg.WatchlistServiceHost, cfg.WatchlistServicePort)
-	userAddr := fmt.Sprintf("%s:%s", cfg.UserServiceHost, cfg.UserServicePort)
+	// Подключение к базе данных и инициализация репозитория и сервиса
+	db, err := utils.SetupDatabase(cfg, logg)
+	if err != nil {
+		log.Fatalf("Failed to setup database: %v", err)
+	}
+
+	// Запуск сервера метрик
+	if cfg.MetricsAddr != "" {
+		if multiHandler, ok := logg.Handler().(*logger.MultiHandler); ok {
+			if err := metrics.RegisterLogBufferMetrics(multiHandler); err != nil {
+				log.Fatalf("Failed to register log buffer metrics: %v", err)
+			}
+		}
+		metricsServer := metrics.StartServer(cfg.MetricsAddr, logg)
+		defer metricsServer.Close()
+	}
+
+	// Применение миграций схемы базы данных
+	if err := migrations.Run(db, logg); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	mediaCfg := repository.DownstreamConfig{
+		Name:          "media",
+		Addr:          fmt.Sprintf("%s:%s", cfg.MediaServiceHost, cfg.MediaServicePort),
+		Target:        cfg.MediaServiceTarget,
+		TLS:           cfg.MediaServiceTLS,
+		CAFile:        cfg.MediaServiceCAFile,
+		AllowInsecure: cfg.InsecureGRPC,
+		RoundRobin:    cfg.DownstreamRoundRobin,
+		WaitForReady:  cfg.DownstreamWaitForReady,
+		CallTimeout:   cfg.DownstreamCallTimeout,
+		Compression:   cfg.DownstreamCompression,
+		Concurrency:   cfg.MediaConcurrency,
+	}
+	reviewCfg := repository.DownstreamConfig{
+		Name:          "review",
+		Addr:          fmt.Sprintf("%s:%s", cfg.ReviewServiceHost, cfg.ReviewServicePort),
+		Target:        cfg.ReviewServiceTarget,
+		TLS:           cfg.ReviewServiceTLS,
+		CAFile:        cfg.ReviewServiceCAFile,
+		AllowInsecure: cfg.InsecureGRPC,
+		RoundRobin:    cfg.DownstreamRoundRobin,
+		WaitForReady:  cfg.DownstreamWaitForReady,
+		CallTimeout:   cfg.DownstreamCallTimeout,
+		Compression:   cfg.DownstreamCompression,
+		Concurrency:   cfg.ReviewConcurrency,
+	}
+	watchlistCfg := repository.DownstreamConfig{
+		Name:          "watchlist",
+		Addr:          fmt.Sprintf("%s:%s", cfg.WatchlistServiceHost, cfg.WatchlistServicePort),
+		Target:        cfg.WatchlistServiceTarget,
+		TLS:           cfg.WatchlistServiceTLS,
+		CAFile:        cfg.WatchlistServiceCAFile,
+		AllowInsecure: cfg.InsecureGRPC,
+		RoundRobin:    cfg.DownstreamRoundRobin,
+		WaitForReady:  cfg.DownstreamWaitForReady,
+		CallTimeout:   cfg.DownstreamCallTimeout,
+		Compression:   cfg.DownstreamCompression,
+		Concurrency:   cfg.WatchlistConcurrency,
+	}
+	userCfg := repository.DownstreamConfig{
+		Name:          "user",
+		Addr:          fmt.Sprintf("%s:%s", cfg.UserServiceHost, cfg.UserServicePort),
+		Target:        cfg.UserServiceTarget,
+		TLS:           cfg.UserServiceTLS,
+		CAFile:        cfg.UserServiceCAFile,
+		AllowInsecure: cfg.InsecureGRPC,
+		RoundRobin:    cfg.DownstreamRoundRobin,
+		WaitForReady:  cfg.DownstreamWaitForReady,
+		CallTimeout:   cfg.DownstreamCallTimeout,
+		Compression:   cfg.DownstreamCompression,
+		Concurrency:   cfg.UserConcurrency,
+	}
 
 	// Инициализация репозитория и сервиса
-	repo, err := repository.NewPostgresSubscriptionRepository(db, logg, mediaAddr, reviewAddr, watchlistAddr, userAddr)
+	usernameNormalization, err := repository.ParseUsernameNormalization(cfg.UsernameNormalization)
+	if err != nil {
+		log.Fatalf("Invalid username normalization: %v", err)
+	}
+	repoOptions := repository.Options{
+		UsernameCacheTTL:      cfg.UsernameCacheTTL,
+		UsernameNormalization: usernameNormalization,
+		GraphQueryTimeout:     cfg.GraphQueryTimeout,
+		GraphMaxFanout:        cfg.GraphMaxFanout,
+	}
+	if cfg.DownstreamStartupCheck {
+		repoOptions.StartupCheckTimeout = cfg.DownstreamCheckTimeout
+	}
+	repo, err := repository.NewPostgresSubscriptionRepository(db, logg, mediaCfg, reviewCfg, watchlistCfg, userCfg, repoOptions)
 	if err != nil {
 		log.Fatalf("Failed to create repository: %v", err)
 	}
+	var serviceRepo repository.SubscriptionRepository = repo
+	if cfg.FeedCacheTTL > 0 {
+		serviceRepo = repository.NewCachedFeedRepository(repo, cfg.FeedCacheTTL, repository.HeavyUserTTL{
+			Threshold: cfg.HeavyFeedThreshold,
+			TTL:       cfg.HeavyFeedCacheTTL,
+		}, cfg.FeedCacheMaxEntries)
+	}
+	if cfg.FeedSingleflight {
+		// Одновременные промахи кэша лент тоже объединяются, поэтому слой стоит поверх кэша
+		serviceRepo = repository.NewSingleflightFeedRepository(serviceRepo)
+	}
+	if cfg.SubscriptionCheckTTL > 0 {
+		serviceRepo = repository.NewCachedSubscriptionCheckRepository(serviceRepo, cfg.SubscriptionCheckTTL, cfg.NegativeCheckTTL)
+	}
+
+	// Инициализация публикации доменных событий подписок; без Kafka события только передаются наблюдателям
+	var eventPublisher service.EventPublisher
+	if cfg.KafkaEnabled {
+		partitionKey, err := events.ParsePartitionKeyStrategy(cfg.EventsPartitionKey)
+		if err != nil {
+			log.Fatalf("Invalid events partition key: %v", err)
+		}
+		eventsAcks, err := events.ParseRequiredAcks(cfg.EventsAcks)
+		if err != nil {
+			log.Fatalf("Invalid events acks level: %v", err)
+		}
+		eventsProducerOptions := events.ProducerOptions{
+			RequiredAcks: eventsAcks,
+			RetryMax:     cfg.EventsRetryMax,
+			AckTimeout:   cfg.EventsAckTimeout,
+		}
+		eventsKafkaPublisher, err := events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.EventsTopic, eventsProducerOptions, logg)
+		if err != nil {
+			log.Fatalf("Failed to create events publisher: %v", err)
+		}
+		var eventsPublisher events.Publisher = eventsKafkaPublisher
+		if cfg.EventsQueueSize > 0 {
+			queuePolicy, err := events.ParseQueuePolicy(cfg.EventsQueuePolicy)
+			if err != nil {
+				log.Fatalf("Invalid events queue policy: %v", err)
+			}
+			eventsPublisher = events.NewAsyncPublisher(eventsKafkaPublisher, cfg.EventsTopic, cfg.EventsQueueSize, queuePolicy, logg)
+		}
+		// При остановке сервиса Close отправляет события, оставшиеся в очереди
+		defer eventsPublisher.Close()
+		eventPublisher = events.NewSubscriptionEventPublisher(eventsPublisher, partitionKey)
+	} else {
+		logg.Warn("Kafka is disabled, subscription events are not published")
+	}
 
-	subscriptionService := service.NewSubscriptionService(repo, logg)
+	selfSubscribePolicy, err := service.ParseSelfSubscribePolicy(cfg.SelfSubscribePolicy)
+	if err != nil {
+		log.Fatalf("Invalid self-subscribe policy: %v", err)
+	}
+
+	subscriptionService := service.NewSubscriptionService(serviceRepo, logg, eventPublisher, service.Options{
+		DefaultPageSize:       cfg.DefaultPageSize,
+		MaxPageSize:           cfg.MaxPageSize,
+		SelfSubscribePolicy:   selfSubscribePolicy,
+		IdempotentUnsubscribe: cfg.IdempotentUnsubscribe,
+		FeedMaxItems:          cfg.FeedMaxItems,
+		FeedCallBudget:        cfg.FeedCallBudget,
+		FeedDeadlineMargin:    cfg.FeedDeadlineMargin,
+		FeedConcurrency:       cfg.FeedConcurrency,
+		WatchBufferSize:       cfg.WatchBufferSize,
+	})
+
+	// Контекст завершается по SIGINT или SIGTERM, после чего сервер и фоновые задачи останавливаются
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Запуск очистки мягко удаленных подписок
+	retentionPurge := jobs.NewRetentionPurge(repo, cfg.PurgeInterval, cfg.PurgeRetention, cfg.PurgeBatchSize, logg)
+	go retentionPurge.Run(ctx)
+
+	// Запуск рассылки уведомлений о новых отзывах, если задана тема событий отзывов
+	if cfg.ReviewEventsTopic != "" {
+		publisher, err := events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.NotificationTopic, events.DefaultProducerOptions(), logg)
+		if err != nil {
+			log.Fatalf("Failed to create notification publisher: %v", err)
+		}
+		defer publisher.Close()
+
+		reviewConsumer, err := events.NewReviewConsumer(cfg.KafkaBrokers, cfg.KafkaConsumerGroup, cfg.ReviewEventsTopic, subscriptionService, publisher, cfg.NotificationMaxFanout, logg)
+		if err != nil {
+			log.Fatalf("Failed to create review consumer: %v", err)
+		}
+		defer reviewConsumer.Close()
+
+		go func() {
+			if err := reviewConsumer.Run(ctx); err != nil {
+				logg.Error("review consumer stopped", slog.Any("error", err))
+			}
+		}()
+	}
 
 	// Запуск gRPC-сервера
-	if err := utils.StartGrpcServer(cfg, subscriptionService); err != nil {
+	if err := utils.StartGrpcServer(ctx, cfg, subscriptionService, repo); err != nil {
 		log.Fatalf("Failed to start gRPC server: %v", err)
 	}
 }
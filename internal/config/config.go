@@ -2,34 +2,107 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strconv"
-	"strings"
+	"net"
+	"time"
 
+	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
 
-// Config содержит параметры конфигурации приложения
+// Config содержит параметры конфигурации приложения.
+// Значения читаются из переменных окружения по тегам env; envDefault задает значение по умолчанию
 type Config struct {
-	DBHost               string   // Хост базы данных
-	DBPort               string   // Порт базы данных
-	DBUser               string   // Пользователь базы данных
-	DBPassword           string   // Пароль базы данных
-	DBName               string   // Имя базы данных
-	DBSSLMode            string   // Режим SSL для базы данных
-	KafkaBrokers         []string // Список брокеров Kafka
-	KafkaTopic           string   // Тема Kafka
-	GRPCPort             string   // Порт для gRPC сервиса
-	ServiceName          string   // Имя сервиса
-	LogBufferSize        int      // Размер буфера для логов
-	MediaServiceHost     string   // Хост сервиса медиа
-	MediaServicePort     string   // Порт сервиса медиа
-	ReviewServiceHost    string   // Хост сервиса отзывов
-	ReviewServicePort    string   // Порт сервиса отзывов
-	WatchlistServiceHost string   // Хост сервиса вотчлистов
-	WatchlistServicePort string   // Порт сервиса вотчлистов
-	UserServiceHost      string   // Хост сервиса пользователей
-	UserServicePort      string   // Порт сервиса пользователей
+	DBHost                 string        `env:"DB_HOST,required,notEmpty"`                                  // Хост базы данных
+	DBPort                 string        `env:"DB_PORT,required,notEmpty"`                                  // Порт базы данных
+	DBUser                 string        `env:"DB_USER,required,notEmpty"`                                  // Пользователь базы данных
+	DBPassword             string        `env:"DB_PASSWORD,required,notEmpty"`                              // Пароль базы данных
+	DBName                 string        `env:"DB_NAME,required,notEmpty"`                                  // Имя базы данных
+	DBSSLMode              string        `env:"DB_SSLMODE,required,notEmpty"`                               // Режим SSL для базы данных
+	DBStatementTimeout     time.Duration `env:"DB_STATEMENT_TIMEOUT" envDefault:"30s"`                      // Максимальное время выполнения запроса к базе данных (0 — без ограничения)
+	DBApplicationName      string        `env:"DB_APPLICATION_NAME,expand" envDefault:"${SERVICE_NAME}"`    // Имя подключения в pg_stat_activity (по умолчанию SERVICE_NAME)
+	DBLogLevel             string        `env:"DB_LOG_LEVEL" envDefault:"silent"`                           // Логирование SQL-запросов: silent (по умолчанию), error, warn (также медленные запросы) или info (все запросы)
+	DBSlowQueryThreshold   time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" envDefault:"200ms"`                 // Время выполнения, начиная с которого запрос логируется как медленный (0 — не выделять медленные запросы)
+	KafkaEnabled           bool          `env:"KAFKA_ENABLED" envDefault:"true"`                            // Использовать Kafka для логов и событий; false — логи только в stdout, события не публикуются
+	KafkaBrokers           []string      `env:"KAFKA_BROKERS" envSeparator:","`                             // Список брокеров Kafka в формате host:port (обязателен при KAFKA_ENABLED)
+	KafkaTopic             string        `env:"KAFKA_TOPIC"`                                                // Тема Kafka для логов (обязательна при KAFKA_ENABLED)
+	GRPCPort               string        `env:"GRPC_PORT,required,notEmpty"`                                // Порт для gRPC сервиса
+	DisabledRPCs           []string      `env:"DISABLED_RPCS" envSeparator:","`                             // Методы gRPC, вызовы которых отклоняются с кодом Unimplemented (например, Subscribe,Unsubscribe)
+	ServiceName            string        `env:"SERVICE_NAME,required,notEmpty"`                             // Имя сервиса
+	LogBufferSize          int           `env:"LOG_BUFFER_SIZE" envDefault:"100"`                           // Размер буфера для логов; неположительное значение заменяется на 100
+	RawLogBufferSize       int           `env:"-"`                                                          // Значение LOG_BUFFER_SIZE до замены значением по умолчанию; заполняется validate()
+	LogCloseTimeout        time.Duration `env:"LOG_CLOSE_TIMEOUT" envDefault:"5s"`                          // Максимальное время отправки буферизованных логов при остановке сервиса
+	MetricsAddr            string        `env:"METRICS_ADDR" envDefault:":9090"`                            // Адрес HTTP-сервера метрик Prometheus (пусто — метрики отключены)
+	LogFormat              string        `env:"LOG_FORMAT" envDefault:"console"`                            // Формат логов в stdout: console (по умолчанию), text или json
+	MediaServiceHost       string        `env:"MEDIA_SERVICE_HOST"`                                         // Хост сервиса медиа
+	MediaServicePort       string        `env:"MEDIA_SERVICE_PORT"`                                         // Порт сервиса медиа
+	MediaServiceTarget     string        `env:"MEDIA_SERVICE_TARGET"`                                       // Полный адрес сервиса медиа для gRPC-клиента (например, dns:///media:50051); заменяет хост и порт
+	MediaConcurrency       int           `env:"MEDIA_SERVICE_CONCURRENCY" envDefault:"64"`                  // Максимальное число одновременных вызовов сервиса медиа (0 — без ограничения)
+	ReviewServiceHost      string        `env:"REVIEW_SERVICE_HOST"`                                        // Хост сервиса отзывов
+	ReviewServicePort      string        `env:"REVIEW_SERVICE_PORT"`                                        // Порт сервиса отзывов
+	ReviewServiceTarget    string        `env:"REVIEW_SERVICE_TARGET"`                                      // Полный адрес сервиса отзывов для gRPC-клиента (например, dns:///review:50053); заменяет хост и порт
+	ReviewConcurrency      int           `env:"REVIEW_SERVICE_CONCURRENCY" envDefault:"32"`                 // Максимальное число одновременных вызовов сервиса отзывов (0 — без ограничения)
+	WatchlistServiceHost   string        `env:"WATCHLIST_SERVICE_HOST"`                                     // Хост сервиса вотчлистов
+	WatchlistServicePort   string        `env:"WATCHLIST_SERVICE_PORT"`                                     // Порт сервиса вотчлистов
+	WatchlistServiceTarget string        `env:"WATCHLIST_SERVICE_TARGET"`                                   // Полный адрес сервиса вотчлистов для gRPC-клиента (например, dns:///watchlist:50054); заменяет хост и порт
+	WatchlistConcurrency   int           `env:"WATCHLIST_SERVICE_CONCURRENCY" envDefault:"32"`              // Максимальное число одновременных вызовов сервиса вотчлистов (0 — без ограничения)
+	UserServiceHost        string        `env:"USER_SERVICE_HOST"`                                          // Хост сервиса пользователей
+	UserServicePort        string        `env:"USER_SERVICE_PORT"`                                          // Порт сервиса пользователей
+	UserServiceTarget      string        `env:"USER_SERVICE_TARGET"`                                        // Полный адрес сервиса пользователей для gRPC-клиента (например, dns:///user:50052); заменяет хост и порт
+	UserConcurrency        int           `env:"USER_SERVICE_CONCURRENCY" envDefault:"64"`                   // Максимальное число одновременных вызовов сервиса пользователей (0 — без ограничения)
+	ReviewEventsTopic      string        `env:"REVIEW_EVENTS_TOPIC"`                                        // Тема Kafka с событиями создания отзывов (пусто — потребитель отключен)
+	NotificationTopic      string        `env:"NOTIFICATION_TOPIC" envDefault:"subscription_notifications"` // Тема Kafka для уведомлений подписчикам
+	NotificationMaxFanout  int           `env:"NOTIFICATION_MAX_FANOUT" envDefault:"1000"`                  // Максимальное число подписчиков, получающих уведомление об одном отзыве
+	KafkaConsumerGroup     string        `env:"KAFKA_CONSUMER_GROUP,expand" envDefault:"${SERVICE_NAME}"`   // Группа потребителей Kafka (по умолчанию SERVICE_NAME)
+	GRPCCompression        bool          `env:"GRPC_COMPRESSION"`                                           // Сжимать ответы gzip для клиентов, поддерживающих gzip
+	GRPCTLSCertFile        string        `env:"GRPC_TLS_CERT_FILE"`                                         // Путь к сертификату gRPC-сервера (пусто — без TLS)
+	GRPCTLSKeyFile         string        `env:"GRPC_TLS_KEY_FILE"`                                          // Путь к ключу сертификата gRPC-сервера
+	GRPCTLSClientCAFile    string        `env:"GRPC_TLS_CLIENT_CA_FILE"`                                    // Путь к CA клиентских сертификатов для mTLS (пусто — без проверки клиента)
+	MediaServiceTLS        bool          `env:"MEDIA_SERVICE_TLS"`                                          // Использовать TLS для подключения к сервису медиа
+	MediaServiceCAFile     string        `env:"MEDIA_SERVICE_CA_FILE"`                                      // Путь к CA сервиса медиа (пусто — системные корневые сертификаты)
+	ReviewServiceTLS       bool          `env:"REVIEW_SERVICE_TLS"`                                         // Использовать TLS для подключения к сервису отзывов
+	ReviewServiceCAFile    string        `env:"REVIEW_SERVICE_CA_FILE"`                                     // Путь к CA сервиса отзывов
+	WatchlistServiceTLS    bool          `env:"WATCHLIST_SERVICE_TLS"`                                      // Использовать TLS для подключения к сервису вотчлистов
+	WatchlistServiceCAFile string        `env:"WATCHLIST_SERVICE_CA_FILE"`                                  // Путь к CA сервиса вотчлистов
+	UserServiceTLS         bool          `env:"USER_SERVICE_TLS"`                                           // Использовать TLS для подключения к сервису пользователей
+	UserServiceCAFile      string        `env:"USER_SERVICE_CA_FILE"`                                       // Путь к CA сервиса пользователей
+	DefaultPageSize        int           `env:"DEFAULT_PAGE_SIZE" envDefault:"50"`                          // Размер страницы по умолчанию для постраничных методов
+	MaxPageSize            int           `env:"MAX_PAGE_SIZE" envDefault:"500"`                             // Максимальный размер страницы для постраничных методов
+	SelfSubscribePolicy    string        `env:"SELF_SUBSCRIBE_POLICY"`                                      // Исход подписки на самого себя: reject (по умолчанию, InvalidArgument), ignore (успех без подписки) или allow
+	AllowSelfSubscribe     bool          `env:"ALLOW_SELF_SUBSCRIBE"`                                       // Устарело: то же, что SELF_SUBSCRIBE_POLICY=allow; вместе с другим значением SELF_SUBSCRIBE_POLICY — ошибка
+	IdempotentUnsubscribe  bool          `env:"IDEMPOTENT_UNSUBSCRIBE"`                                     // Отписка от несуществующей подписки завершается успешно вместо NotFound
+	EventsTopic            string        `env:"EVENTS_TOPIC" envDefault:"subscription_domain_events"`       // Тема Kafka для доменных событий подписок
+	EventsPartitionKey     string        `env:"EVENTS_PARTITION_KEY" envDefault:"subscriber"`               // Ключ партиционирования событий: subscriber (по умолчанию), target или random
+	EventsAcks             string        `env:"EVENTS_ACKS" envDefault:"all"`                               // Уровень подтверждения доменных событий брокером: all (по умолчанию), leader или none
+	EventsRetryMax         int           `env:"EVENTS_RETRY_MAX" envDefault:"5"`                            // Число повторных попыток отправки доменного события
+	EventsQueueSize        int           `env:"EVENTS_QUEUE_SIZE" envDefault:"1000"`                        // Размер очереди асинхронной публикации доменных событий (0 — синхронная публикация)
+	EventsQueuePolicy      string        `env:"EVENTS_QUEUE_POLICY" envDefault:"block"`                     // Поведение при заполненной очереди событий: block (по умолчанию) или drop
+	EventsAckTimeout       time.Duration `env:"EVENTS_ACK_TIMEOUT" envDefault:"10s"`                        // Время ожидания подтверждения доменного события брокером
+	FeedMaxItems           int           `env:"FEED_MAX_ITEMS" envDefault:"1000"`                           // Максимальное число элементов в ленте подписок
+	FeedCallBudget         int           `env:"FEED_CALL_BUDGET" envDefault:"3000"`                         // Максимальное число вызовов внешних сервисов при построении одной ленты (0 — без ограничения)
+	FeedDeadlineMargin     time.Duration `env:"FEED_DEADLINE_MARGIN" envDefault:"200ms"`                    // Запас до дедлайна запроса для возврата частично собранной ленты
+	FeedConcurrency        int           `env:"FEED_CONCURRENCY" envDefault:"8"`                            // Число подписок, обрабатываемых одновременно при построении ленты
+	WatchBufferSize        int           `env:"WATCH_BUFFER_SIZE" envDefault:"16"`                          // Число событий, ожидающих отправки одному наблюдателю за изменениями подписок
+	FeedCacheTTL           time.Duration `env:"FEED_CACHE_TTL" envDefault:"0s"`                             // Время кэширования страниц лент (0 — без кэша); кэш пользователя сбрасывается при изменении его подписок
+	HeavyFeedThreshold     int           `env:"FEED_CACHE_HEAVY_THRESHOLD" envDefault:"0"`                  // Число подписок, начиная с которого ленты пользователя хранятся FEED_CACHE_HEAVY_TTL (0 — не переопределять)
+	HeavyFeedCacheTTL      time.Duration `env:"FEED_CACHE_HEAVY_TTL" envDefault:"0s"`                       // Время кэширования лент пользователей с большим числом подписок
+	FeedCacheMaxEntries    int           `env:"FEED_CACHE_MAX_ENTRIES" envDefault:"10000"`                  // Максимальное число страниц в каждом кэше лент (0 — без ограничения); при заполнении вытесняются страницы с ближайшим истечением срока
+	FeedSingleflight       bool          `env:"FEED_SINGLEFLIGHT"`                                          // Объединять одновременные одинаковые запросы лент в одно построение
+	UsernameCacheTTL       time.Duration `env:"USERNAME_CACHE_TTL" envDefault:"0s"`                         // Время кэширования имен пользователей в ленте (0 — без кэша)
+	SubscriptionCheckTTL   time.Duration `env:"SUBSCRIPTION_CHECK_TTL" envDefault:"0s"`                     // Время кэширования найденных подписок при проверке IsSubscribed (0 — без кэша)
+	NegativeCheckTTL       time.Duration `env:"SUBSCRIPTION_CHECK_NEGATIVE_TTL" envDefault:"0s"`            // Время кэширования отсутствия подписки (0 — не кэшировать); не больше SUBSCRIPTION_CHECK_TTL
+	UsernameNormalization  string        `env:"USERNAME_NORMALIZATION" envDefault:"none"`                   // Нормализация имен пользователей в ленте: none (по умолчанию), trim или lower
+	GraphQueryTimeout      time.Duration `env:"GRAPH_QUERY_TIMEOUT" envDefault:"2s"`                        // Ограничение времени тяжелых запросов по графу подписок (0 — без ограничения)
+	GraphMaxFanout         int           `env:"GRAPH_MAX_FANOUT" envDefault:"5000"`                         // Число подписок пользователя, учитываемых в запросах второго уровня (0 — без ограничения)
+	DownstreamStartupCheck bool          `env:"DOWNSTREAM_STARTUP_CHECK"`                                   // Проверять доступность внешних сервисов при запуске (результат только логируется)
+	DownstreamCheckTimeout time.Duration `env:"DOWNSTREAM_CHECK_TIMEOUT" envDefault:"5s"`                   // Время ожидания каждого внешнего сервиса при проверке доступности
+	InsecureGRPC           bool          `env:"INSECURE_GRPC" envDefault:"true"`                            // Разрешить подключения к внешним сервисам без TLS (только для разработки)
+	DownstreamRoundRobin   bool          `env:"DOWNSTREAM_ROUND_ROBIN"`                                     // Балансировать вызовы внешних сервисов round_robin по адресам из DNS
+	DownstreamWaitForReady bool          `env:"DOWNSTREAM_WAIT_FOR_READY"`                                  // Ждать переподключения к внешнему сервису вместо немедленной ошибки Unavailable
+	DownstreamCallTimeout  time.Duration `env:"DOWNSTREAM_CALL_TIMEOUT" envDefault:"0s"`                    // Максимальное время одного вызова внешнего сервиса (0 — до дедлайна запроса)
+	DownstreamCompression  bool          `env:"DOWNSTREAM_COMPRESSION"`                                     // Сжимать запросы к внешним сервисам gzip и запрашивать сжатые ответы
+	PurgeInterval          time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`                             // Интервал очистки мягко удаленных подписок
+	PurgeRetention         time.Duration `env:"PURGE_RETENTION" envDefault:"720h"`                          // Срок хранения мягко удаленных подписок
+	PurgeBatchSize         int           `env:"PURGE_BATCH_SIZE" envDefault:"1000"`                         // Число строк, удаляемых за один запрос очистки
 }
 
 // LoadConfig загружает конфигурацию из .env файла
@@ -40,55 +113,224 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to load .env file: %w", err)
 	}
 
-	// Проверяем обязательные переменные окружения
-	requiredEnvVars := []string{
-		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD",
-		"DB_NAME", "DB_SSLMODE", "KAFKA_BROKERS", "KAFKA_TOPIC",
-		"GRPC_PORT", "SERVICE_NAME", "LOG_BUFFER_SIZE",
-		"MEDIA_SERVICE_HOST", "MEDIA_SERVICE_PORT",
-		"REVIEW_SERVICE_HOST", "REVIEW_SERVICE_PORT",
-		"WATCHLIST_SERVICE_HOST", "WATCHLIST_SERVICE_PORT",
-		"USER_SERVICE_HOST", "USER_SERVICE_PORT",
+	// Разбираем переменные окружения по тегам структуры, проверяя обязательные переменные
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse environment: %w", err)
 	}
 
-	for _, envVar := range requiredEnvVars {
-		if value := os.Getenv(envVar); value == "" {
-			return nil, fmt.Errorf("missing required environment variable: %s", envVar)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// validateKafkaBrokers проверяет, что список брокеров не пуст и каждый брокер задан в формате host:port
+func validateKafkaBrokers(brokers []string) error {
+	if len(brokers) == 0 {
+		return fmt.Errorf("KAFKA_BROKERS is required when KAFKA_ENABLED is true")
+	}
+	for _, broker := range brokers {
+		host, port, err := net.SplitHostPort(broker)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("invalid KAFKA_BROKERS value %q: must be host:port", broker)
+		}
+	}
+	return nil
+}
+
+// validate проверяет согласованность параметров и подставляет значения по умолчанию вместо неположительных
+func (cfg *Config) validate() error {
+	if cfg.KafkaEnabled {
+		if err := validateKafkaBrokers(cfg.KafkaBrokers); err != nil {
+			return err
+		}
+		if cfg.KafkaTopic == "" {
+			return fmt.Errorf("KAFKA_TOPIC is required when KAFKA_ENABLED is true")
+		}
+	} else {
+		if cfg.ReviewEventsTopic != "" {
+			return fmt.Errorf("REVIEW_EVENTS_TOPIC requires KAFKA_ENABLED")
+		}
+		// Без Kafka логгер использует только stdout
+		cfg.KafkaBrokers = nil
+	}
+
+	// Устаревший флаг переводится в политику; явно заданная другая политика ему противоречит
+	if cfg.AllowSelfSubscribe {
+		switch cfg.SelfSubscribePolicy {
+		case "", "allow":
+			cfg.SelfSubscribePolicy = "allow"
+		default:
+			return fmt.Errorf("ALLOW_SELF_SUBSCRIBE conflicts with SELF_SUBSCRIBE_POLICY=%s: remove the deprecated ALLOW_SELF_SUBSCRIBE", cfg.SelfSubscribePolicy)
+		}
+	}
+
+	if cfg.LogCloseTimeout <= 0 {
+		return fmt.Errorf("LOG_CLOSE_TIMEOUT must be positive")
+	}
+
+	// Неположительные размеры заменяются значениями по умолчанию
+	cfg.RawLogBufferSize = cfg.LogBufferSize
+	if cfg.LogBufferSize <= 0 {
+		cfg.LogBufferSize = 100
+	}
+	if cfg.NotificationMaxFanout <= 0 {
+		cfg.NotificationMaxFanout = 1000
+	}
+	if cfg.DefaultPageSize <= 0 {
+		cfg.DefaultPageSize = 50
+	}
+	if cfg.MaxPageSize <= 0 {
+		cfg.MaxPageSize = 500
+	}
+	if cfg.FeedMaxItems <= 0 {
+		cfg.FeedMaxItems = 1000
+	}
+	if cfg.WatchBufferSize <= 0 {
+		cfg.WatchBufferSize = 16
+	}
+	if cfg.PurgeBatchSize <= 0 {
+		cfg.PurgeBatchSize = 1000
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = time.Hour
+	}
+	if cfg.PurgeRetention <= 0 {
+		cfg.PurgeRetention = 30 * 24 * time.Hour
+	}
+
+	if cfg.DBStatementTimeout < 0 {
+		return fmt.Errorf("DB_STATEMENT_TIMEOUT must not be negative")
+	}
+	if cfg.DBSlowQueryThreshold < 0 {
+		return fmt.Errorf("DB_SLOW_QUERY_THRESHOLD must not be negative")
+	}
+	if cfg.FeedCacheTTL < 0 {
+		return fmt.Errorf("FEED_CACHE_TTL must not be negative")
+	}
+	if cfg.FeedCacheMaxEntries < 0 {
+		return fmt.Errorf("FEED_CACHE_MAX_ENTRIES must not be negative")
+	}
+	if cfg.HeavyFeedThreshold < 0 {
+		return fmt.Errorf("FEED_CACHE_HEAVY_THRESHOLD must not be negative")
+	}
+	if cfg.HeavyFeedThreshold > 0 {
+		if cfg.FeedCacheTTL == 0 {
+			return fmt.Errorf("FEED_CACHE_HEAVY_THRESHOLD requires FEED_CACHE_TTL")
 		}
+		if cfg.HeavyFeedCacheTTL <= 0 {
+			return fmt.Errorf("FEED_CACHE_HEAVY_TTL must be positive when FEED_CACHE_HEAVY_THRESHOLD is set")
+		}
+	}
+	if cfg.SubscriptionCheckTTL < 0 {
+		return fmt.Errorf("SUBSCRIPTION_CHECK_TTL must not be negative")
+	}
+	if cfg.NegativeCheckTTL < 0 {
+		return fmt.Errorf("SUBSCRIPTION_CHECK_NEGATIVE_TTL must not be negative")
+	}
+	// Отсутствие подписки перестает быть верным сразу после подписки, поэтому хранится не дольше ее наличия
+	if cfg.NegativeCheckTTL > cfg.SubscriptionCheckTTL {
+		return fmt.Errorf("SUBSCRIPTION_CHECK_NEGATIVE_TTL must not exceed SUBSCRIPTION_CHECK_TTL")
+	}
+	if cfg.GraphQueryTimeout < 0 {
+		return fmt.Errorf("GRAPH_QUERY_TIMEOUT must not be negative")
+	}
+	if cfg.EventsQueueSize < 0 {
+		return fmt.Errorf("EVENTS_QUEUE_SIZE must not be negative")
+	}
+	if cfg.EventsRetryMax < 0 {
+		return fmt.Errorf("EVENTS_RETRY_MAX must not be negative")
+	}
+	if cfg.EventsAckTimeout <= 0 {
+		return fmt.Errorf("EVENTS_ACK_TIMEOUT must be positive")
+	}
+	downstreamConcurrency := []struct {
+		envVar string
+		value  int
+	}{
+		{"MEDIA_SERVICE_CONCURRENCY", cfg.MediaConcurrency},
+		{"REVIEW_SERVICE_CONCURRENCY", cfg.ReviewConcurrency},
+		{"WATCHLIST_SERVICE_CONCURRENCY", cfg.WatchlistConcurrency},
+		{"USER_SERVICE_CONCURRENCY", cfg.UserConcurrency},
+	}
+	for _, concurrency := range downstreamConcurrency {
+		if concurrency.value < 0 {
+			return fmt.Errorf("%s must not be negative", concurrency.envVar)
+		}
+	}
+	if cfg.DownstreamCallTimeout < 0 {
+		return fmt.Errorf("DOWNSTREAM_CALL_TIMEOUT must not be negative")
+	}
+	if cfg.GraphMaxFanout < 0 {
+		return fmt.Errorf("GRAPH_MAX_FANOUT must not be negative")
+	}
+
+	if cfg.DefaultPageSize > cfg.MaxPageSize {
+		return fmt.Errorf("DEFAULT_PAGE_SIZE must not exceed MAX_PAGE_SIZE")
+	}
+
+	if cfg.FeedDeadlineMargin < 0 {
+		return fmt.Errorf("FEED_DEADLINE_MARGIN must not be negative")
+	}
+	if cfg.FeedCallBudget < 0 {
+		return fmt.Errorf("FEED_CALL_BUDGET must not be negative")
+	}
+
+	// Значение 1 включает последовательный обход подписок
+	if cfg.FeedConcurrency < 1 || cfg.FeedConcurrency > 64 {
+		return fmt.Errorf("invalid FEED_CONCURRENCY value: must be between 1 and 64")
+	}
+
+	// Адрес каждого внешнего сервиса задается полным адресом или парой хост и порт
+	downstreamAddrs := []struct {
+		name   string
+		target string
+		host   string
+		port   string
+	}{
+		{"MEDIA_SERVICE", cfg.MediaServiceTarget, cfg.MediaServiceHost, cfg.MediaServicePort},
+		{"REVIEW_SERVICE", cfg.ReviewServiceTarget, cfg.ReviewServiceHost, cfg.ReviewServicePort},
+		{"WATCHLIST_SERVICE", cfg.WatchlistServiceTarget, cfg.WatchlistServiceHost, cfg.WatchlistServicePort},
+		{"USER_SERVICE", cfg.UserServiceTarget, cfg.UserServiceHost, cfg.UserServicePort},
+	}
+	for _, service := range downstreamAddrs {
+		if service.target == "" && (service.host == "" || service.port == "") {
+			return fmt.Errorf("%s_TARGET or both %s_HOST and %s_PORT must be set", service.name, service.name, service.name)
+		}
+	}
+
+	// Без INSECURE_GRPC все внешние сервисы должны использовать TLS
+	if !cfg.InsecureGRPC {
+		downstreamTLS := []struct {
+			envVar  string
+			enabled bool
+		}{
+			{"MEDIA_SERVICE_TLS", cfg.MediaServiceTLS},
+			{"REVIEW_SERVICE_TLS", cfg.ReviewServiceTLS},
+			{"WATCHLIST_SERVICE_TLS", cfg.WatchlistServiceTLS},
+			{"USER_SERVICE_TLS", cfg.UserServiceTLS},
+		}
+		for _, service := range downstreamTLS {
+			if !service.enabled {
+				return fmt.Errorf("%s must be enabled when INSECURE_GRPC is false", service.envVar)
+			}
+		}
+	}
+
+	// Доменные события публикуются отдельно от логов
+	if cfg.EventsTopic == cfg.KafkaTopic {
+		return fmt.Errorf("EVENTS_TOPIC must differ from KAFKA_TOPIC used for logs")
+	}
+
+	// Сертификат и ключ gRPC-сервера задаются только вместе
+	if (cfg.GRPCTLSCertFile == "") != (cfg.GRPCTLSKeyFile == "") {
+		return fmt.Errorf("GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE must be set together")
+	}
+	if cfg.GRPCTLSClientCAFile != "" && cfg.GRPCTLSCertFile == "" {
+		return fmt.Errorf("GRPC_TLS_CLIENT_CA_FILE requires GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE")
 	}
 
-	// Преобразуем KAFKA_BROKERS в []string
-	kafkaBrokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
-	if len(kafkaBrokers) == 0 || (len(kafkaBrokers) == 1 && kafkaBrokers[0] == "") {
-		return nil, fmt.Errorf("invalid KAFKA_BROKERS value")
-	}
-
-	// Преобразуем LOG_BUFFER_SIZE в int с дефолтным значением 100, если не задано корректно
-	logBufferSize, err := strconv.Atoi(os.Getenv("LOG_BUFFER_SIZE"))
-	if err != nil || logBufferSize <= 0 {
-		logBufferSize = 100 // Значение по умолчанию
-	}
-
-	// Возвращаем конфигурацию
-	return &Config{
-		DBHost:               os.Getenv("DB_HOST"),
-		DBPort:               os.Getenv("DB_PORT"),
-		DBUser:               os.Getenv("DB_USER"),
-		DBPassword:           os.Getenv("DB_PASSWORD"),
-		DBName:               os.Getenv("DB_NAME"),
-		DBSSLMode:            os.Getenv("DB_SSLMODE"),
-		KafkaBrokers:         kafkaBrokers,
-		KafkaTopic:           os.Getenv("KAFKA_TOPIC"),
-		GRPCPort:             os.Getenv("GRPC_PORT"),
-		ServiceName:          os.Getenv("SERVICE_NAME"),
-		LogBufferSize:        logBufferSize,
-		MediaServiceHost:     os.Getenv("MEDIA_SERVICE_HOST"),
-		MediaServicePort:     os.Getenv("MEDIA_SERVICE_PORT"),
-		ReviewServiceHost:    os.Getenv("REVIEW_SERVICE_HOST"),
-		ReviewServicePort:    os.Getenv("REVIEW_SERVICE_PORT"),
-		WatchlistServiceHost: os.Getenv("WATCHLIST_SERVICE_HOST"),
-		WatchlistServicePort: os.Getenv("WATCHLIST_SERVICE_PORT"),
-		UserServiceHost:      os.Getenv("USER_SERVICE_HOST"),
-		UserServicePort:      os.Getenv("USER_SERVICE_PORT"),
-	}, nil
+	return nil
 }
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// parseConfig разбирает и проверяет конфигурацию из обязательных переменных и переменных overrides
+func parseConfig(t *testing.T, overrides map[string]string) (*Config, error) {
+	t.Helper()
+
+	environment := map[string]string{
+		"DB_HOST":       "localhost",
+		"DB_PORT":       "5432",
+		"DB_USER":       "user",
+		"DB_PASSWORD":   "password",
+		"DB_NAME":       "subscription",
+		"DB_SSLMODE":    "disable",
+		"GRPC_PORT":     "50051",
+		"SERVICE_NAME":  "subscription",
+		"KAFKA_ENABLED": "false",
+
+		"MEDIA_SERVICE_TARGET":     "passthrough:///media:50051",
+		"REVIEW_SERVICE_TARGET":    "passthrough:///review:50053",
+		"WATCHLIST_SERVICE_TARGET": "passthrough:///watchlist:50054",
+		"USER_SERVICE_TARGET":      "passthrough:///user:50052",
+	}
+	for key, value := range overrides {
+		environment[key] = value
+	}
+
+	var cfg Config
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: environment}); err != nil {
+		t.Fatalf("parse environment: %v", err)
+	}
+	return &cfg, cfg.validate()
+}
+
+func TestSelfSubscribePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default", overrides: nil, want: ""},
+		{name: "explicit policy", overrides: map[string]string{"SELF_SUBSCRIBE_POLICY": "ignore"}, want: "ignore"},
+		{name: "legacy flag alone", overrides: map[string]string{"ALLOW_SELF_SUBSCRIBE": "true"}, want: "allow"},
+		{name: "legacy flag with allow", overrides: map[string]string{"ALLOW_SELF_SUBSCRIBE": "true", "SELF_SUBSCRIBE_POLICY": "allow"}, want: "allow"},
+		{name: "legacy flag with reject", overrides: map[string]string{"ALLOW_SELF_SUBSCRIBE": "true", "SELF_SUBSCRIBE_POLICY": "reject"}, wantErr: true},
+		{name: "legacy flag with ignore", overrides: map[string]string{"ALLOW_SELF_SUBSCRIBE": "true", "SELF_SUBSCRIBE_POLICY": "ignore"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(t, tt.overrides)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("validate accepted a conflicting configuration, policy %q", cfg.SelfSubscribePolicy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if cfg.SelfSubscribePolicy != tt.want {
+				t.Errorf("got policy %q, want %q", cfg.SelfSubscribePolicy, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogBufferSizeFallsBackToDefault(t *testing.T) {
+	tests := []struct {
+		configured string
+		want       int
+	}{
+		{configured: "250", want: 250},
+		{configured: "0", want: 100},
+		{configured: "-5", want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.configured, func(t *testing.T) {
+			cfg, err := parseConfig(t, map[string]string{"LOG_BUFFER_SIZE": tt.configured})
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if cfg.LogBufferSize != tt.want {
+				t.Errorf("got buffer size %d, want %d", cfg.LogBufferSize, tt.want)
+			}
+			if got := strconv.Itoa(cfg.RawLogBufferSize); got != tt.configured {
+				t.Errorf("got configured buffer size %s, want %s", got, tt.configured)
+			}
+		})
+	}
+}
+
+func TestGraphMaxFanout(t *testing.T) {
+	cfg, err := parseConfig(t, map[string]string{"GRAPH_MAX_FANOUT": "0"})
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.GraphMaxFanout != 0 {
+		t.Errorf("got fan-out cap %d, want 0 (unlimited)", cfg.GraphMaxFanout)
+	}
+
+	if _, err := parseConfig(t, map[string]string{"GRAPH_MAX_FANOUT": "-1"}); err == nil {
+		t.Error("validate accepted a negative GRAPH_MAX_FANOUT")
+	}
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/watchlist-kata/subscription/internal/metrics"
+)
+
+// QueuePolicy определяет поведение асинхронной публикации при заполненной очереди
+type QueuePolicy string
+
+// Политики заполненной очереди
+const (
+	// QueueBlock ждет освобождения места в очереди, но не дольше, чем позволяет контекст запроса (по умолчанию)
+	QueueBlock QueuePolicy = "block"
+	// QueueDrop отбрасывает событие и учитывает его в метрике
+	QueueDrop QueuePolicy = "drop"
+)
+
+// maxAsyncBatch — максимальное число событий, отправляемых одним запросом к брокеру
+const maxAsyncBatch = 100
+
+// ErrPublisherClosed возвращается при публикации в AsyncPublisher после Close
+var ErrPublisherClosed = errors.New("publisher is closed")
+
+// ParseQueuePolicy проверяет и возвращает политику заполненной очереди
+func ParseQueuePolicy(value string) (QueuePolicy, error) {
+	switch policy := QueuePolicy(value); policy {
+	case QueueBlock, QueueDrop:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown queue policy: %q", value)
+	}
+}
+
+// AsyncPublisher публикует события через ограниченную очередь в отдельной горутине,
+// чтобы медленный брокер не увеличивал время обработки запросов
+type AsyncPublisher struct {
+	next   Publisher
+	topic  string
+	policy QueuePolicy
+	queue  chan Message
+	done   chan struct{}
+	mu     sync.RWMutex // Защищает closed: очередь закрывается только когда в нее никто не пишет
+	closed bool
+	logger *slog.Logger
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewAsyncPublisher создает новый экземпляр AsyncPublisher поверх next и запускает отправку событий.
+// topic используется только в метриках
+func NewAsyncPublisher(next Publisher, topic string, size int, policy QueuePolicy, logger *slog.Logger) *AsyncPublisher {
+	p := &AsyncPublisher{
+		next:   next,
+		topic:  topic,
+		policy: policy,
+		queue:  make(chan Message, size),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go p.run()
+	return p
+}
+
+// Publish ставит событие в очередь
+func (p *AsyncPublisher) Publish(ctx context.Context, key string, event any) error {
+	return p.PublishBatch(ctx, []Message{{Key: key, Event: event}})
+}
+
+// PublishBatch ставит события в очередь. Ошибка доставки в брокер не возвращается: она логируется при отправке.
+// После Close возвращает ErrPublisherClosed
+func (p *AsyncPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		metrics.RecordEventsDropped(p.topic, len(messages))
+		return fmt.Errorf("failed to enqueue %d events: %w", len(messages), ErrPublisherClosed)
+	}
+
+	for i, message := range messages {
+		if p.policy == QueueDrop {
+			select {
+			case p.queue <- message:
+			default:
+				metrics.RecordEventsDropped(p.topic, 1)
+				p.logger.WarnContext(ctx, "event queue is full, dropping event")
+			}
+			continue
+		}
+
+		select {
+		case p.queue <- message:
+		case <-ctx.Done():
+			dropped := len(messages) - i
+			metrics.RecordEventsDropped(p.topic, dropped)
+			return fmt.Errorf("failed to enqueue %d events: %w", dropped, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// run отправляет события из очереди, объединяя накопившиеся события в один запрос к брокеру
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+	for message := range p.queue {
+		batch := []Message{message}
+	drain:
+		for len(batch) < maxAsyncBatch {
+			select {
+			case next, ok := <-p.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		// Контекст запроса к этому моменту может быть завершен, поэтому отправка выполняется без него
+		if err := p.next.PublishBatch(context.Background(), batch); err != nil {
+			p.logger.Error("failed to publish queued events", slog.Int("count", len(batch)), slog.Any("error", err))
+		}
+	}
+}
+
+// Close перестает принимать события, отправляет оставшиеся в очереди и закрывает следующий Publisher.
+// Повторные вызовы не закрывают следующий Publisher еще раз и возвращают результат первого вызова
+func (p *AsyncPublisher) Close() error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		close(p.queue)
+		p.mu.Unlock()
+
+		<-p.done
+		p.closeErr = p.next.Close()
+	})
+	return p.closeErr
+}
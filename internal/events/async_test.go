@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// recordingPublisher запоминает опубликованные события и число вызовов Close
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	closes   int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, key string, event any) error {
+	return p.PublishBatch(ctx, []Message{{Key: key, Event: event}})
+}
+
+func (p *recordingPublisher) PublishBatch(_ context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closes++
+	if p.closes > 1 {
+		// KafkaPublisher при повторном закрытии продюсера паникует
+		panic("publisher closed twice")
+	}
+	return nil
+}
+
+func TestAsyncPublisherRejectsEventsAfterClose(t *testing.T) {
+	for _, policy := range []QueuePolicy{QueueBlock, QueueDrop} {
+		t.Run(string(policy), func(t *testing.T) {
+			next := &recordingPublisher{}
+			p := NewAsyncPublisher(next, "test", 1, policy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			if err := p.Publish(context.Background(), "1", "before close"); err != nil {
+				t.Fatalf("Publish before Close: %v", err)
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if len(next.messages) != 1 {
+				t.Errorf("got %d delivered events, want 1", len(next.messages))
+			}
+
+			err := p.PublishBatch(context.Background(), []Message{{Key: "2", Event: "after close"}})
+			if !errors.Is(err, ErrPublisherClosed) {
+				t.Errorf("PublishBatch after Close returned %v, want ErrPublisherClosed", err)
+			}
+			if err := p.Close(); err != nil {
+				t.Errorf("second Close: %v", err)
+			}
+			if next.closes != 1 {
+				t.Errorf("next publisher closed %d times, want 1", next.closes)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReviewCreatedEvent представляет событие создания отзыва, публикуемое сервисом отзывов
+type ReviewCreatedEvent struct {
+	ReviewID int64  `json:"id"`
+	MediaID  int64  `json:"media_id"`
+	UserID   int64  `json:"user_id"`
+	Rating   int32  `json:"rating"`
+	Created  string `json:"created_at"`
+}
+
+// ReviewNotificationEvent представляет уведомление подписчику о новом отзыве автора, на которого он подписан
+type ReviewNotificationEvent struct {
+	EventID      string    `json:"event_id"`
+	SubscriberID uint      `json:"subscriber_id"`
+	AuthorID     uint      `json:"author_id"`
+	ReviewID     int64     `json:"review_id"`
+	MediaID      int64     `json:"media_id"`
+	Rating       int32     `json:"rating"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// reviewNotificationID формирует детерминированный идентификатор уведомления,
+// позволяющий получателям отбрасывать повторные доставки
+func reviewNotificationID(reviewID int64, subscriberID uint) string {
+	return fmt.Sprintf("review:%d:subscriber:%d", reviewID, subscriberID)
+}
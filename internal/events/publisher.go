@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/watchlist-kata/subscription/internal/metrics"
+)
+
+// Publisher публикует события в Kafka
+type Publisher interface {
+	Publish(ctx context.Context, key string, event any) error
+	PublishBatch(ctx context.Context, messages []Message) error
+	Close() error
+}
+
+// Message представляет событие с ключом партиционирования
+type Message struct {
+	Key   string // Ключ партиционирования (пусто — случайная партиция)
+	Event any
+}
+
+// KafkaPublisher реализует Publisher поверх синхронного продюсера sarama
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+	logger   *slog.Logger
+}
+
+// ProducerOptions содержит настройки надежности доставки продюсера
+type ProducerOptions struct {
+	RequiredAcks sarama.RequiredAcks // Уровень подтверждения записи брокером
+	RetryMax     int                 // Число повторных попыток отправки
+	AckTimeout   time.Duration       // Время ожидания подтверждения записи брокером (0 — значение sarama по умолчанию)
+}
+
+// DefaultProducerOptions возвращает настройки, при которых событие подтверждается всеми репликами
+func DefaultProducerOptions() ProducerOptions {
+	return ProducerOptions{RequiredAcks: sarama.WaitForAll, RetryMax: 5}
+}
+
+// ParseRequiredAcks возвращает уровень подтверждения записи по имени: all, leader или none
+func ParseRequiredAcks(value string) (sarama.RequiredAcks, error) {
+	switch value {
+	case "all":
+		return sarama.WaitForAll, nil
+	case "leader":
+		return sarama.WaitForLocal, nil
+	case "none":
+		return sarama.NoResponse, nil
+	default:
+		return 0, fmt.Errorf("unknown acks level: %q", value)
+	}
+}
+
+// NewKafkaPublisher создает новый экземпляр KafkaPublisher
+func NewKafkaPublisher(brokers []string, topic string, options ProducerOptions, logger *slog.Logger) (*KafkaPublisher, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = options.RequiredAcks
+	config.Producer.Retry.Max = options.RetryMax
+	if options.AckTimeout > 0 {
+		config.Producer.Timeout = options.AckTimeout
+	}
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync producer: %w", err)
+	}
+
+	return &KafkaPublisher{
+		producer: producer,
+		topic:    topic,
+		logger:   logger,
+	}, nil
+}
+
+// Publish публикует одно событие
+func (p *KafkaPublisher) Publish(ctx context.Context, key string, event any) error {
+	return p.PublishBatch(ctx, []Message{{Key: key, Event: event}})
+}
+
+// PublishBatch публикует набор событий одним запросом к брокеру
+func (p *KafkaPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	producerMessages := make([]*sarama.ProducerMessage, 0, len(messages))
+	for _, message := range messages {
+		payload, err := json.Marshal(message.Event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		producerMessage := &sarama.ProducerMessage{
+			Topic: p.topic,
+			Value: sarama.ByteEncoder(payload),
+		}
+		// Без ключа партиция выбирается случайно
+		if message.Key != "" {
+			producerMessage.Key = sarama.StringEncoder(message.Key)
+		}
+		producerMessages = append(producerMessages, producerMessage)
+	}
+
+	if err := p.producer.SendMessages(producerMessages); err != nil {
+		// Продюсер возвращает ошибку после исчерпания повторных попыток
+		failed := len(producerMessages)
+		var producerErrors sarama.ProducerErrors
+		if errors.As(err, &producerErrors) {
+			failed = len(producerErrors)
+		}
+		metrics.RecordEventPublishFailures(p.topic, failed)
+		p.logger.ErrorContext(ctx, "failed to publish events", slog.Int("failed", failed), slog.Any("error", err))
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+
+	return nil
+}
+
+// Close закрывает продюсер
+func (p *KafkaPublisher) Close() error {
+	if err := p.producer.Close(); err != nil {
+		return fmt.Errorf("failed to close producer: %w", err)
+	}
+	return nil
+}
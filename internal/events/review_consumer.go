@@ -0,0 +1,230 @@
+package events
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// notificationBatchSize ограничивает число уведомлений в одном запросе к брокеру
+	notificationBatchSize = 100
+	// processedReviewsCapacity ограничивает число запоминаемых обработанных отзывов
+	processedReviewsCapacity = 10000
+)
+
+// SubscriberLister возвращает подписчиков пользователя
+type SubscriberLister interface {
+	GetSubscribers(ctx context.Context, userID uint) ([]uint, error)
+}
+
+// ReviewConsumer читает события создания отзывов и рассылает уведомления подписчикам автора
+type ReviewConsumer struct {
+	group       sarama.ConsumerGroup
+	topic       string
+	subscribers SubscriberLister
+	publisher   Publisher
+	maxFanout   int
+	logger      *slog.Logger
+	processed   *recentSet
+}
+
+// NewReviewConsumer создает новый экземпляр ReviewConsumer
+func NewReviewConsumer(brokers []string, groupID string, topic string, subscribers SubscriberLister, publisher Publisher, maxFanout int, logger *slog.Logger) (*ReviewConsumer, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	config.Consumer.Return.Errors = true
+
+	group, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	return &ReviewConsumer{
+		group:       group,
+		topic:       topic,
+		subscribers: subscribers,
+		publisher:   publisher,
+		maxFanout:   maxFanout,
+		logger:      logger,
+		processed:   newRecentSet(processedReviewsCapacity),
+	}, nil
+}
+
+// Run читает события до отмены контекста
+func (c *ReviewConsumer) Run(ctx context.Context) error {
+	go func() {
+		for err := range c.group.Errors() {
+			c.logger.Error("review consumer error", slog.Any("error", err))
+		}
+	}()
+
+	for {
+		if err := c.group.Consume(ctx, []string{c.topic}, c); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+			c.logger.ErrorContext(ctx, "failed to consume review events", slog.Any("error", err))
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Close останавливает группу потребителей
+func (c *ReviewConsumer) Close() error {
+	if err := c.group.Close(); err != nil {
+		return fmt.Errorf("failed to close consumer group: %w", err)
+	}
+	return nil
+}
+
+// Setup вызывается перед началом чтения партиций
+func (c *ReviewConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup вызывается после завершения чтения партиций
+func (c *ReviewConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim обрабатывает сообщения партиции; смещение фиксируется только после успешной рассылки
+func (c *ReviewConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := c.handleMessage(ctx, message); err != nil {
+				c.logger.ErrorContext(ctx, "failed to handle review event", slog.Any("error", err))
+				return err
+			}
+			session.MarkMessage(message, "")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handleMessage рассылает уведомления подписчикам автора отзыва
+func (c *ReviewConsumer) handleMessage(ctx context.Context, message *sarama.ConsumerMessage) error {
+	var event ReviewCreatedEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		// Повторная обработка некорректного сообщения ничего не даст, поэтому пропускаем его
+		c.logger.WarnContext(ctx, "skipping malformed review event", slog.Any("error", err))
+		return nil
+	}
+	if event.ReviewID <= 0 || event.UserID <= 0 {
+		c.logger.WarnContext(ctx, "skipping review event without ids")
+		return nil
+	}
+
+	if c.processed.Contains(event.ReviewID) {
+		c.logger.InfoContext(ctx, "review event already processed", slog.Int64("review_id", event.ReviewID))
+		return nil
+	}
+
+	authorID := uint(event.UserID)
+	subscriberIDs, err := c.subscribers.GetSubscribers(ctx, authorID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscribers: %w", err)
+	}
+
+	if len(subscriberIDs) > c.maxFanout {
+		c.logger.WarnContext(ctx, "review notification fan-out truncated",
+			slog.Int64("review_id", event.ReviewID),
+			slog.Int("subscribers", len(subscriberIDs)),
+			slog.Int("max_fanout", c.maxFanout))
+		subscriberIDs = subscriberIDs[:c.maxFanout]
+	}
+
+	createdAt := message.Timestamp
+	if parsed, err := time.Parse(time.RFC3339, event.Created); err == nil {
+		createdAt = parsed
+	}
+
+	batch := make([]Message, 0, notificationBatchSize)
+	for _, subscriberID := range subscriberIDs {
+		batch = append(batch, Message{
+			Key: strconv.FormatUint(uint64(subscriberID), 10),
+			Event: ReviewNotificationEvent{
+				EventID:      reviewNotificationID(event.ReviewID, subscriberID),
+				SubscriberID: subscriberID,
+				AuthorID:     authorID,
+				ReviewID:     event.ReviewID,
+				MediaID:      event.MediaID,
+				Rating:       event.Rating,
+				CreatedAt:    createdAt,
+			},
+		})
+		if len(batch) == notificationBatchSize {
+			if err := c.publisher.PublishBatch(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := c.publisher.PublishBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	c.processed.Add(event.ReviewID)
+	c.logger.InfoContext(ctx, "review notifications published",
+		slog.Int64("review_id", event.ReviewID),
+		slog.Int("subscribers", len(subscriberIDs)))
+	return nil
+}
+
+// recentSet хранит ограниченное число последних обработанных идентификаторов
+type recentSet struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[int64]*list.Element
+}
+
+// newRecentSet создает новый экземпляр recentSet
+func newRecentSet(capacity int) *recentSet {
+	return &recentSet{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[int64]*list.Element, capacity),
+	}
+}
+
+// Contains проверяет, был ли идентификатор обработан недавно
+func (s *recentSet) Contains(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[id]
+	return ok
+}
+
+// Add запоминает идентификатор, вытесняя самый старый при переполнении
+func (s *recentSet) Add(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; ok {
+		return
+	}
+	s.items[id] = s.order.PushBack(id)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(int64))
+	}
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Типы событий подписок
+const (
+	SubscriptionCreated = "subscription.created"
+	SubscriptionDeleted = "subscription.deleted"
+)
+
+// SubscriptionEvent представляет доменное событие изменения подписки
+type SubscriptionEvent struct {
+	Type         string    `json:"type"`
+	SubscriberID uint      `json:"subscriber_id"`
+	UserID       uint      `json:"user_id"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// PartitionKeyStrategy определяет, по какому полю события выбирается партиция
+type PartitionKeyStrategy string
+
+// Стратегии выбора ключа партиционирования
+const (
+	// PartitionBySubscriber сохраняет порядок событий одного подписчика (по умолчанию)
+	PartitionBySubscriber PartitionKeyStrategy = "subscriber"
+	// PartitionByTarget сохраняет порядок событий для пользователя, на которого подписываются
+	PartitionByTarget PartitionKeyStrategy = "target"
+	// PartitionRandom распределяет события по партициям без гарантий порядка
+	PartitionRandom PartitionKeyStrategy = "random"
+)
+
+// ParsePartitionKeyStrategy проверяет и возвращает стратегию партиционирования
+func ParsePartitionKeyStrategy(value string) (PartitionKeyStrategy, error) {
+	switch strategy := PartitionKeyStrategy(value); strategy {
+	case PartitionBySubscriber, PartitionByTarget, PartitionRandom:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown partition key strategy: %q", value)
+	}
+}
+
+// key возвращает ключ партиционирования для события
+func (s PartitionKeyStrategy) key(event SubscriptionEvent) string {
+	switch s {
+	case PartitionByTarget:
+		return strconv.FormatUint(uint64(event.UserID), 10)
+	case PartitionRandom:
+		return ""
+	default:
+		return strconv.FormatUint(uint64(event.SubscriberID), 10)
+	}
+}
+
+// SubscriptionEventPublisher публикует доменные события подписок
+type SubscriptionEventPublisher struct {
+	publisher Publisher
+	strategy  PartitionKeyStrategy
+}
+
+// NewSubscriptionEventPublisher создает новый экземпляр SubscriptionEventPublisher
+func NewSubscriptionEventPublisher(publisher Publisher, strategy PartitionKeyStrategy) *SubscriptionEventPublisher {
+	return &SubscriptionEventPublisher{
+		publisher: publisher,
+		strategy:  strategy,
+	}
+}
+
+// PublishSubscriptionEvent публикует событие подписки с ключом согласно стратегии партиционирования
+func (p *SubscriptionEventPublisher) PublishSubscriptionEvent(ctx context.Context, event SubscriptionEvent) error {
+	return p.publisher.Publish(ctx, p.strategy.key(event), event)
+}
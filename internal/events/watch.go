@@ -0,0 +1,96 @@
+package events
+
+import "sync"
+
+// ChangeHub рассылает доменные события подписок наблюдателям внутри процесса.
+// Наблюдатель получает события, в которых пользователь является подписчиком
+type ChangeHub struct {
+	mu       sync.Mutex
+	buffer   int
+	watchers map[uint]map[*ChangeWatcher]struct{}
+}
+
+// NewChangeHub создает новый экземпляр ChangeHub; buffer задает число событий, ожидающих обработки у одного наблюдателя
+func NewChangeHub(buffer int) *ChangeHub {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &ChangeHub{
+		buffer:   buffer,
+		watchers: make(map[uint]map[*ChangeWatcher]struct{}),
+	}
+}
+
+// ChangeWatcher получает события подписок одного пользователя
+type ChangeWatcher struct {
+	hub        *ChangeHub
+	userID     uint
+	events     chan SubscriptionEvent
+	overflowed bool
+	closed     bool
+}
+
+// Watch регистрирует наблюдателя за изменениями подписок пользователя userID.
+// Наблюдателя нужно закрыть вызовом Close
+func (h *ChangeHub) Watch(userID uint) *ChangeWatcher {
+	watcher := &ChangeWatcher{
+		hub:    h,
+		userID: userID,
+		events: make(chan SubscriptionEvent, h.buffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.watchers[userID] == nil {
+		h.watchers[userID] = make(map[*ChangeWatcher]struct{})
+	}
+	h.watchers[userID][watcher] = struct{}{}
+	return watcher
+}
+
+// Notify передает событие наблюдателям подписчика без блокировки. Наблюдатель, буфер которого заполнен,
+// отключается: его канал закрывается, а Overflowed сообщает, что события были пропущены
+func (h *ChangeHub) Notify(event SubscriptionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for watcher := range h.watchers[event.SubscriberID] {
+		select {
+		case watcher.events <- event:
+		default:
+			watcher.overflowed = true
+			h.removeLocked(watcher)
+		}
+	}
+}
+
+// removeLocked снимает регистрацию наблюдателя и закрывает его канал; вызывается под h.mu
+func (h *ChangeHub) removeLocked(watcher *ChangeWatcher) {
+	if watcher.closed {
+		return
+	}
+	watcher.closed = true
+	close(watcher.events)
+	delete(h.watchers[watcher.userID], watcher)
+	if len(h.watchers[watcher.userID]) == 0 {
+		delete(h.watchers, watcher.userID)
+	}
+}
+
+// Events возвращает канал событий; канал закрывается при Close или переполнении буфера
+func (w *ChangeWatcher) Events() <-chan SubscriptionEvent {
+	return w.events
+}
+
+// Overflowed сообщает, что наблюдатель отключен из-за переполнения буфера и часть событий пропущена
+func (w *ChangeWatcher) Overflowed() bool {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	return w.overflowed
+}
+
+// Close снимает регистрацию наблюдателя
+func (w *ChangeWatcher) Close() {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	w.hub.removeLocked(w)
+}
@@ -0,0 +1,58 @@
+// Package jobs содержит фоновые задачи сервиса
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DeletedSubscriptionsPurger окончательно удаляет мягко удаленные подписки
+type DeletedSubscriptionsPurger interface {
+	PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error)
+}
+
+// RetentionPurge периодически удаляет подписки, мягко удаленные раньше срока хранения
+type RetentionPurge struct {
+	purger    DeletedSubscriptionsPurger
+	interval  time.Duration
+	retention time.Duration
+	batchSize int
+	logger    *slog.Logger
+}
+
+// NewRetentionPurge создает новый экземпляр RetentionPurge
+func NewRetentionPurge(purger DeletedSubscriptionsPurger, interval time.Duration, retention time.Duration, batchSize int, logger *slog.Logger) *RetentionPurge {
+	return &RetentionPurge{
+		purger:    purger,
+		interval:  interval,
+		retention: retention,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run выполняет очистку с заданным интервалом до отмены контекста
+func (p *RetentionPurge) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.purge(ctx)
+		}
+	}
+}
+
+// purge выполняет один проход очистки
+func (p *RetentionPurge) purge(ctx context.Context) {
+	deletedBefore := time.Now().Add(-p.retention)
+	purged, err := p.purger.PurgeDeletedSubscriptions(ctx, deletedBefore, p.batchSize)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "retention purge failed", slog.Int64("purged", purged), slog.Any("error", err))
+		return
+	}
+	p.logger.InfoContext(ctx, "retention purge completed", slog.Int64("purged", purged), slog.Time("deleted_before", deletedBefore))
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Причины удаления записей из кэша
+const (
+	// EvictionExpired — запись удалена по истечении срока хранения
+	EvictionExpired = "expired"
+	// EvictionInvalidated — запись удалена, потому что исходные данные изменились
+	EvictionInvalidated = "invalidated"
+	// EvictionCapacity — запись удалена, чтобы освободить место в заполненном кэше
+	EvictionCapacity = "capacity"
+)
+
+var (
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_cache_lookups_total",
+		Help: "Cache lookups by cache name and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	cacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_cache_evictions_total",
+		Help: "Entries removed from a cache by cache name and reason (expired, invalidated or capacity).",
+	}, []string{"cache", "reason"})
+
+	cacheTTLUtilization = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "subscription_cache_ttl_utilization_ratio",
+		Help:    "Share of the TTL elapsed when a cached entry was served, by cache name.",
+		Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+	}, []string{"cache"})
+)
+
+// RecordCacheHit учитывает обращение к кэшу, обслуженное из кэша. ttlUsed — доля срока хранения записи,
+// прошедшая к моменту обращения (от 0 до 1)
+func RecordCacheHit(cache string, ttlUsed float64) {
+	cacheLookups.WithLabelValues(cache, "hit").Inc()
+	cacheTTLUtilization.WithLabelValues(cache).Observe(ttlUsed)
+}
+
+// RecordCacheMiss учитывает обращение к кэшу, не найденное в кэше
+func RecordCacheMiss(cache string) {
+	cacheLookups.WithLabelValues(cache, "miss").Inc()
+}
+
+// RecordCacheEvictions учитывает записи, удаленные из кэша по причине reason
+func RecordCacheEvictions(cache string, reason string, count int) {
+	if count > 0 {
+		cacheEvictions.WithLabelValues(cache, reason).Add(float64(count))
+	}
+}
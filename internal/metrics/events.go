@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var eventPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "subscription_events_publish_failures_total",
+	Help: "Events that could not be delivered to Kafka after all retries.",
+}, []string{"topic"})
+
+// RecordEventPublishFailures учитывает события, которые не удалось доставить в Kafka после всех повторных попыток
+func RecordEventPublishFailures(topic string, count int) {
+	eventPublishFailures.WithLabelValues(topic).Add(float64(count))
+}
+
+var eventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "subscription_events_dropped_total",
+	Help: "Events dropped before publishing because the in-process queue was full.",
+}, []string{"topic"})
+
+// RecordEventsDropped учитывает события, отброшенные из-за заполненной очереди публикации
+func RecordEventsDropped(topic string, count int) {
+	eventsDropped.WithLabelValues(topic).Add(float64(count))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageSizeBuckets — границы гистограмм размеров сообщений: от 64 байт до 16 МБ
+var messageSizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
+var (
+	requestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "subscription_grpc_request_size_bytes",
+		Help:    "Serialized size of gRPC request messages.",
+		Buckets: messageSizeBuckets,
+	}, []string{"method"})
+	responseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "subscription_grpc_response_size_bytes",
+		Help:    "Serialized size of gRPC response messages.",
+		Buckets: messageSizeBuckets,
+	}, []string{"method"})
+	downstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "subscription_downstream_call_duration_seconds",
+		Help:    "Duration of calls to downstream gRPC services.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"dependency", "method", "code"})
+)
+
+// UnaryServerInterceptor записывает размеры сериализованных запросов и ответов в гистограммы с меткой метода.
+// Ответы с ошибкой не учитываются
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if message, ok := req.(proto.Message); ok {
+			requestSize.WithLabelValues(info.FullMethod).Observe(float64(proto.Size(message)))
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		if message, ok := resp.(proto.Message); ok {
+			responseSize.WithLabelValues(info.FullMethod).Observe(float64(proto.Size(message)))
+		}
+		return resp, nil
+	}
+}
+
+// UnaryClientInterceptor записывает длительность вызовов внешнего сервиса dependency в гистограмму
+// с метками сервиса, метода и кода ответа. Метки не содержат ID из запросов, поэтому число рядов ограничено
+func UnaryClientInterceptor(dependency string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		downstreamDuration.WithLabelValues(dependency, method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,66 @@
+// Package metrics публикует метрики сервиса в формате Prometheus
+package metrics
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/watchlist-kata/subscription/pkg/logger"
+)
+
+// LogBufferStatsSource возвращает статистику буферов асинхронных обработчиков логов
+type LogBufferStatsSource interface {
+	BufferStats() []logger.BufferStats
+}
+
+// logBufferCollector публикует размер буферов логов и число отброшенных записей
+type logBufferCollector struct {
+	source      LogBufferStatsSource
+	sizeDesc    *prometheus.Desc
+	droppedDesc *prometheus.Desc
+}
+
+// RegisterLogBufferMetrics регистрирует метрики буферов логов
+func RegisterLogBufferMetrics(source LogBufferStatsSource) error {
+	return prometheus.Register(&logBufferCollector{
+		source: source,
+		sizeDesc: prometheus.NewDesc("subscription_log_buffer_size",
+			"Capacity of the asynchronous log buffer.", []string{"sink"}, nil),
+		droppedDesc: prometheus.NewDesc("subscription_log_dropped_total",
+			"Log records dropped because the buffer was full.", []string{"sink"}, nil),
+	})
+}
+
+// Describe реализует prometheus.Collector
+func (c *logBufferCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sizeDesc
+	ch <- c.droppedDesc
+}
+
+// Collect реализует prometheus.Collector
+func (c *logBufferCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.source.BufferStats() {
+		ch <- prometheus.MustNewConstMetric(c.sizeDesc, prometheus.GaugeValue, float64(stats.BufferSize), stats.Sink)
+		ch <- prometheus.MustNewConstMetric(c.droppedDesc, prometheus.CounterValue, float64(stats.Dropped), stats.Sink)
+	}
+}
+
+// StartServer запускает HTTP-сервер с метриками по пути /metrics в отдельной горутине
+func StartServer(addr string, log *slog.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("metrics server stopped", slog.Any("error", err))
+		}
+	}()
+
+	log.Info("metrics server started", slog.String("addr", addr))
+	return server
+}
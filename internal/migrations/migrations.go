@@ -0,0 +1,110 @@
+// Package migrations применяет версионированные SQL-миграции схемы базы данных
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// advisoryLockID — ключ advisory-блокировки, не дающей нескольким репликам применять миграции одновременно
+const advisoryLockID = 7302215
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// migration представляет одну версионированную миграцию
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Run применяет еще не примененные миграции по возрастанию версии, каждую в отдельной транзакции
+func Run(db *gorm.DB, logger *slog.Logger) error {
+	migrations, err := load()
+	if err != nil {
+		return err
+	}
+
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`).Error; err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	for _, m := range migrations {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Миграции могут выполняться дольше statement_timeout из настроек подключения
+			if err := tx.Exec("SET LOCAL statement_timeout = 0").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockID).Error; err != nil {
+				return err
+			}
+
+			var count int64
+			if err := tx.Table("schema_migrations").Where("version = ?", m.version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := tx.Exec(m.sql).Error; err != nil {
+				return err
+			}
+			applied = true
+			return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %04d_%s: %w", m.version, m.name, err)
+		}
+		if applied {
+			logger.Info("migration applied", slog.Int("version", m.version), slog.String("name", m.name))
+		}
+	}
+
+	return nil
+}
+
+// load читает встроенные файлы миграций вида NNNN_name.sql
+func load() ([]migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		fileName := entry.Name()
+		versionPart, name, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", fileName, err)
+		}
+		content, err := fs.ReadFile(files, path.Join("sql", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
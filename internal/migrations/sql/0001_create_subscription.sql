@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS subscription (
+    id            BIGSERIAL PRIMARY KEY,
+    subscriber_id BIGINT      NOT NULL,
+    user_id       BIGINT      NOT NULL,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ
+);
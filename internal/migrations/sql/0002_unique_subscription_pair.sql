@@ -0,0 +1,9 @@
+-- Удаляем дубликаты пар (subscriber_id, user_id), оставляя самую раннюю запись
+DELETE FROM subscription AS s
+USING subscription AS d
+WHERE s.subscriber_id = d.subscriber_id
+  AND s.user_id = d.user_id
+  AND s.id > d.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_subscriber_user
+    ON subscription (subscriber_id, user_id);
@@ -0,0 +1,9 @@
+ALTER TABLE subscription ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_subscription_deleted_at ON subscription (deleted_at);
+
+-- Уникальность пары проверяется только среди действующих подписок, чтобы можно было подписаться повторно
+DROP INDEX IF EXISTS idx_subscription_subscriber_user;
+CREATE UNIQUE INDEX idx_subscription_subscriber_user
+    ON subscription (subscriber_id, user_id)
+    WHERE deleted_at IS NULL;
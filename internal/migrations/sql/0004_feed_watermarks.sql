@@ -0,0 +1,6 @@
+-- Момент, до которого пользователь просмотрел ленту подписок
+CREATE TABLE IF NOT EXISTS feed_watermarks (
+    user_id      BIGINT PRIMARY KEY,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+
+	"github.com/watchlist-kata/protos/media"
+)
+
+// batchFeedViewerConcurrency — число лент, которые GetFeedsForUsers строит одновременно.
+// Каждая лента обходит подписки с параллельностью FeedOptions.Concurrency
+const batchFeedViewerConcurrency = 4
+
+// GetFeedsForUsers строит ленты отзывов для нескольких пользователей. Медиа и имена пользователей запрашиваются
+// у внешних сервисов не больше одного раза за вызов и используются всеми лентами, поэтому пересекающиеся подписки
+// не умножают число вызовов. Одновременно строится не больше batchFeedViewerConcurrency лент.
+// Предназначен для фоновых задач: ошибка построения любой ленты прерывает весь вызов
+func (r *PostgresSubscriptionRepository) GetFeedsForUsers(ctx context.Context, userIDs []uint, opts FeedOptions) (map[uint]*ReviewFeed, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetFeedsForUsers operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	// Копия репозитория отличается только общими на время вызова резолверами медиа и имен
+	batch := *r
+	batch.mediaClient = newMemoMediaClient(r.mediaClient)
+	batch.usernames = newMemoUsernameResolver(r.usernames)
+
+	var mu sync.Mutex
+	feeds := make(map[uint]*ReviewFeed, len(userIDs))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(batchFeedViewerConcurrency)
+	seen := make(map[uint]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		group.Go(func() error {
+			feed, err := batch.GetReviewsBySubscription(groupCtx, userID, opts)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			feeds[userID] = feed
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		r.logger.ErrorContext(ctx, "failed to build feeds for users", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "feeds for users built successfully", slog.Int("users", len(feeds)))
+	return feeds, nil
+}
+
+// memoMediaClient запоминает медиа, полученные у сервиса медиа, и объединяет одновременные запросы одного медиа.
+// Ошибки не запоминаются. Остальные методы передаются клиенту без изменений
+type memoMediaClient struct {
+	media.MediaServiceClient
+	group  singleflight.Group
+	mu     sync.Mutex
+	medias map[int64]*media.Media
+}
+
+// newMemoMediaClient создает новый экземпляр memoMediaClient
+func newMemoMediaClient(next media.MediaServiceClient) *memoMediaClient {
+	return &memoMediaClient{MediaServiceClient: next, medias: make(map[int64]*media.Media)}
+}
+
+// GetMediaByID возвращает запомненное медиа или запрашивает его у сервиса медиа
+func (c *memoMediaClient) GetMediaByID(ctx context.Context, in *media.GetMediaByIDRequest, opts ...grpc.CallOption) (*media.Media, error) {
+	c.mu.Lock()
+	mediaResponse, ok := c.medias[in.Id]
+	c.mu.Unlock()
+	if ok {
+		return mediaResponse, nil
+	}
+
+	result, err, _ := c.group.Do(strconv.FormatInt(in.Id, 10), func() (any, error) {
+		mediaResponse, err := c.MediaServiceClient.GetMediaByID(ctx, in, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.medias[in.Id] = mediaResponse
+		c.mu.Unlock()
+		return mediaResponse, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*media.Media), nil
+}
+
+// memoUsernameResolver запоминает имена пользователей и объединяет одновременные запросы одного имени.
+// Ошибки не запоминаются
+type memoUsernameResolver struct {
+	next      UsernameResolver
+	group     singleflight.Group
+	mu        sync.Mutex
+	usernames map[uint]string
+}
+
+// newMemoUsernameResolver создает новый экземпляр memoUsernameResolver
+func newMemoUsernameResolver(next UsernameResolver) *memoUsernameResolver {
+	return &memoUsernameResolver{next: next, usernames: make(map[uint]string)}
+}
+
+// ResolveUsername возвращает запомненное имя пользователя или получает его у следующего резолвера
+func (r *memoUsernameResolver) ResolveUsername(ctx context.Context, userID uint) (string, error) {
+	r.mu.Lock()
+	username, ok := r.usernames[userID]
+	r.mu.Unlock()
+	if ok {
+		return username, nil
+	}
+
+	result, err, _ := r.group.Do(strconv.FormatUint(uint64(userID), 10), func() (any, error) {
+		username, err := r.next.ResolveUsername(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		r.usernames[userID] = username
+		r.mu.Unlock()
+		return username, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
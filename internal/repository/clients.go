@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+
+	"github.com/watchlist-kata/subscription/internal/metrics"
+)
+
+// DownstreamConfig содержит параметры подключения к внешнему gRPC-сервису
+type DownstreamConfig struct {
+	Name          string // Имя сервиса в метриках длительности вызовов (пусто — длительность не измеряется)
+	Addr          string // Адрес сервиса в формате host:port
+	Target        string // Полный адрес для grpc.NewClient (например, dns:///media:50051); если задан, заменяет Addr без изменений
+	TLS           bool   // Использовать TLS
+	CAFile        string // Путь к CA сервиса (пусто — системные корневые сертификаты)
+	AllowInsecure bool   // Разрешить подключение без TLS
+	RoundRobin    bool   // Распределять вызовы по всем адресам, полученным из DNS
+	// WaitForReady откладывает вызов до переподключения к сервису вместо немедленной ошибки Unavailable,
+	// например во время перезапуска сервиса. Ожидание ограничено CallTimeout и дедлайном запроса
+	WaitForReady bool
+	CallTimeout  time.Duration // Максимальное время одного вызова (0 — без ограничения, кроме дедлайна запроса)
+	Compression  bool          // Сжимать запросы gzip; ответы сервис тогда тоже сжимает
+	// Concurrency ограничивает число одновременных вызовов сервиса со всего процесса (0 — без ограничения).
+	// Вызовы сверх лимита ждут освобождения места в пределах дедлайна запроса
+	Concurrency int
+}
+
+// roundRobinServiceConfig включает балансировку round_robin на стороне клиента
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// dialDownstream создает клиентское подключение к внешнему сервису
+func dialDownstream(cfg DownstreamConfig) (*grpc.ClientConn, error) {
+	creds, err := downstreamCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	target := cfg.Target
+	if target == "" {
+		target = cfg.Addr
+		if cfg.RoundRobin {
+			// Резолвер dns возвращает все адреса реплик, между которыми распределяются вызовы
+			target = "dns:///" + cfg.Addr
+		}
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if cfg.RoundRobin {
+		opts = append(opts, grpc.WithDefaultServiceConfig(roundRobinServiceConfig))
+	}
+	var callOpts []grpc.CallOption
+	if cfg.WaitForReady {
+		callOpts = append(callOpts, grpc.WaitForReady(true))
+	}
+	if cfg.Compression {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	var interceptors []grpc.UnaryClientInterceptor
+	if cfg.Concurrency > 0 {
+		// Ожидание места не входит в CallTimeout, поэтому ограничитель стоит первым
+		interceptors = append(interceptors, concurrencyLimitInterceptor(cfg.Concurrency))
+	}
+	if cfg.Name != "" {
+		// Ожидание места у ограничителя в длительность вызова не входит
+		interceptors = append(interceptors, metrics.UnaryClientInterceptor(cfg.Name))
+	}
+	if cfg.CallTimeout > 0 {
+		interceptors = append(interceptors, callTimeoutInterceptor(cfg.CallTimeout))
+	}
+	if len(interceptors) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(interceptors...))
+	}
+
+	return grpc.NewClient(target, opts...)
+}
+
+// callTimeoutInterceptor ограничивает время каждого вызова внешнего сервиса
+func callTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// concurrencyLimitInterceptor ограничивает число одновременных вызовов внешнего сервиса.
+// Если место не освободилось до завершения ctx, вызов не выполняется и возвращается ошибка контекста
+func concurrencyLimitInterceptor(limit int) grpc.UnaryClientInterceptor {
+	slots := make(chan struct{}, limit)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-slots }()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// displayAddr возвращает адрес сервиса для сообщений об ошибках
+func (cfg DownstreamConfig) displayAddr() string {
+	if cfg.Target != "" {
+		return cfg.Target
+	}
+	return cfg.Addr
+}
+
+// downstreamCredentials возвращает учетные данные транспорта для внешнего сервиса
+func downstreamCredentials(cfg DownstreamConfig) (credentials.TransportCredentials, error) {
+	if !cfg.TLS {
+		if !cfg.AllowInsecure {
+			return nil, fmt.Errorf("TLS is required for %s", cfg.displayAddr())
+		}
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", cfg.CAFile, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// namedConn — подключение к внешнему сервису и имя сервиса для логов
+type namedConn struct {
+	name string
+	conn *grpc.ClientConn
+}
+
+// checkDownstreams пытается установить соединение с каждым внешним сервисом и логирует, какие из них доступны.
+// Недоступность сервиса не прерывает запуск: grpc.NewClient подключается лениво, и без проверки
+// ошибка конфигурации проявилась бы только на первом запросе ленты
+func checkDownstreams(logger *slog.Logger, timeout time.Duration, conns ...namedConn) {
+	for _, downstream := range conns {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := waitForReady(ctx, downstream.conn)
+		cancel()
+		if err != nil {
+			logger.Warn("downstream service is unreachable at startup",
+				slog.String("service", downstream.name), slog.String("target", downstream.conn.Target()), slog.Any("error", err))
+			continue
+		}
+		logger.Info("downstream service is reachable", slog.String("service", downstream.name), slog.String("target", downstream.conn.Target()))
+	}
+}
+
+// waitForReady инициирует подключение и ждет, пока оно перейдет в состояние Ready
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection is %s: %w", state, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// DownstreamError описывает ошибку вызова внешнего сервиса
+type DownstreamError struct {
+	Service string // Имя внешнего сервиса: media, review, watchlist или user
+	Method  string // Вызванный метод внешнего сервиса (пусто, если вызов не выполнялся)
+	Err     error
+	// RetryAfter — время до снятия паузы вызовов недоступного сервиса (0, если пауза не действует)
+	RetryAfter time.Duration
+}
+
+// Error возвращает текст ошибки с именем внешнего сервиса
+func (e *DownstreamError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("%s service: %v", e.Service, e.Err)
+	}
+	return fmt.Sprintf("%s service %s: %v", e.Service, e.Method, e.Err)
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *DownstreamError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/watchlist-kata/protos/subscription"
+)
+
+// FeedOptions задает параметры построения ленты подписок
+type FeedOptions struct {
+	MaxItems    int  // Максимальное число элементов ленты (0 — без ограничения)
+	Page        Page // Страница ленты в пределах MaxItems (Limit 0 — вся лента)
+	Concurrency int  // Число подписок, обрабатываемых одновременно (1 и меньше — последовательно)
+	// CallBudget ограничивает общее число вызовов внешних сервисов при построении ленты (0 — без ограничения).
+	// При исчерпании лимита лента возвращается обрезанной
+	CallBudget int
+	// ForceRefresh требует построить ленту заново в обход кэширующих слоев и обновить их
+	ForceRefresh bool
+	// DeadlineMargin — запас до дедлайна запроса, за который обход подписок прекращается,
+	// чтобы успеть вернуть уже собранную часть ленты (0 — обход до самого дедлайна)
+	DeadlineMargin time.Duration
+	// Raw отключает обогащение ленты вотчлистов: элементы содержат только ID пользователя и медиа,
+	// а сервисы медиа и пользователей не вызываются
+	Raw bool
+	// Shard ограничивает ленту детерминированной частью подписок (нулевое значение — все подписки)
+	Shard FeedShard
+	// IncludeSelf добавляет в ленту элементы самого пользователя; они обрабатываются первыми
+	IncludeSelf bool
+	// SortBy задает порядок элементов ленты отзывов; сортировка применяется к собранной ленте до разбиения на страницы
+	SortBy FeedSort
+	// Since оставляет в ленте только элементы, созданные позже этого момента (нулевое значение — все элементы)
+	Since time.Time
+	// MinRating оставляет в ленте отзывов только отзывы с оценкой не ниже заданной (0 — все отзывы).
+	// Отзывы отбрасываются до ограничения MaxItems и разбиения на страницы
+	MinRating int32
+}
+
+// isUnseen сообщает, создан ли элемент с датой createdAt в формате RFC3339 позже since.
+// При нулевом since подходит любой элемент; элемент с некорректной датой считается просмотренным,
+// чтобы он не оставался непросмотренным навсегда
+func isUnseen(createdAt string, since time.Time) bool {
+	if since.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, createdAt)
+	return err == nil && t.After(since)
+}
+
+// FeedSort задает порядок элементов ленты
+type FeedSort int
+
+const (
+	// FeedSortRecent — сначала новые элементы (по умолчанию)
+	FeedSortRecent FeedSort = iota
+	// FeedSortRating — сначала элементы с наибольшей оценкой, при равной оценке — новые
+	FeedSortRating
+)
+
+// ParseFeedSort возвращает порядок ленты по его имени: recent или rating. Пустая строка означает порядок по умолчанию
+func ParseFeedSort(name string) (FeedSort, error) {
+	switch name {
+	case "", "recent":
+		return FeedSortRecent, nil
+	case "rating":
+		return FeedSortRating, nil
+	default:
+		return FeedSortRecent, fmt.Errorf("unknown feed sort order %q", name)
+	}
+}
+
+// String возвращает имя порядка ленты
+func (s FeedSort) String() string {
+	switch s {
+	case FeedSortRating:
+		return "rating"
+	default:
+		return "recent"
+	}
+}
+
+// WatchlistFeed представляет ленту вотчлистов подписок
+type WatchlistFeed struct {
+	Items      []*subscription.WatchlistItem
+	Truncated  bool // Лента обрезана по MaxItems или бюджету вызовов
+	Incomplete bool // Обход подписок прерван по дедлайну запроса, лента содержит только собранную часть
+}
+
+// ReviewFeed представляет ленту отзывов подписок
+type ReviewFeed struct {
+	Items      []*subscription.ReviewItem
+	Truncated  bool // Лента обрезана по MaxItems или бюджету вызовов
+	Incomplete bool // Обход подписок прерван по дедлайну запроса, лента содержит только собранную часть
+	// CreatedAt содержит даты создания отзывов из Items по ID отзыва. В сообщении subscription.ReviewItem
+	// пока нет поля для даты, поэтому она доступна только внутри сервиса; отзывы с некорректной датой отсутствуют
+	CreatedAt map[int64]time.Time
+}
+
+// feedSourceIDs возвращает пользователей, из активности которых строится лента.
+// При IncludeSelf пользователь добавляется первым, если он еще не входит в подписки; затем применяется Shard
+func feedSourceIDs(userID uint, subscribedToIDs []uint, opts FeedOptions) []uint {
+	if opts.IncludeSelf && !slices.Contains(subscribedToIDs, userID) {
+		subscribedToIDs = append([]uint{userID}, subscribedToIDs...)
+	}
+	return opts.Shard.filter(subscribedToIDs)
+}
+
+// FeedShard задает часть Index из Count, в которую попадают пользователи с user_id % Count == Index.
+// Ленты всех частей вместе содержат те же подписки, что и лента без разбиения
+type FeedShard struct {
+	Index int
+	Count int
+}
+
+// Validate проверяет параметры части; нулевое значение означает ленту без разбиения
+func (s FeedShard) Validate() error {
+	if s == (FeedShard{}) {
+		return nil
+	}
+	if s.Count < 1 {
+		return fmt.Errorf("shard count must be positive")
+	}
+	if s.Index < 0 || s.Index >= s.Count {
+		return fmt.Errorf("shard index must be in [0, %d)", s.Count)
+	}
+	return nil
+}
+
+// filter оставляет пользователей, попадающих в часть
+func (s FeedShard) filter(userIDs []uint) []uint {
+	if s.Count <= 1 {
+		return userIDs
+	}
+	filtered := make([]uint, 0, len(userIDs)/s.Count+1)
+	for _, userID := range userIDs {
+		if userID%uint(s.Count) == uint(s.Index) {
+			filtered = append(filtered, userID)
+		}
+	}
+	return filtered
+}
+
+// sortReviewEntries упорядочивает отзывы ленты. При равных оценке и дате отзывы упорядочиваются по ID автора,
+// затем по ID медиа и ID отзыва, чтобы порядок и границы страниц не зависели от порядка ответов сервисов
+func sortReviewEntries(entries []reviewEntry, sortBy FeedSort) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if sortBy == FeedSortRating && a.review.Rating != b.review.Rating {
+			return a.review.Rating > b.review.Rating
+		}
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		if a.review.UserId != b.review.UserId {
+			return a.review.UserId < b.review.UserId
+		}
+		if a.review.MediaId != b.review.MediaId {
+			return a.review.MediaId < b.review.MediaId
+		}
+		return a.review.Id < b.review.Id
+	})
+}
+
+// sortWatchlistEntries упорядочивает элементы ленты вотчлистов: сначала новые, при равной дате — по ID
+// пользователя и ID медиа, чтобы порядок и границы страниц не зависели от порядка ответов сервисов
+func sortWatchlistEntries(entries []watchlistEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		if a.item.UserId != b.item.UserId {
+			return a.item.UserId < b.item.UserId
+		}
+		return a.item.MediaId < b.item.MediaId
+	})
+}
+
+// applyPage возвращает элементы указанной страницы; при нулевом Limit возвращаются все элементы начиная с Offset
+func applyPage[T any](items []T, page Page) []T {
+	if page.Offset >= len(items) {
+		return items[:0]
+	}
+	items = items[page.Offset:]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// callBudget считает оставшиеся вызовы внешних сервисов в пределах одного запроса ленты.
+// Бюджет разделяется между всеми обработчиками подписок
+type callBudget struct {
+	limited   bool
+	remaining atomic.Int64
+}
+
+// newCallBudget создает бюджет на limit вызовов; при limit 0 и меньше бюджет не ограничен
+func newCallBudget(limit int) *callBudget {
+	budget := &callBudget{limited: limit > 0}
+	budget.remaining.Store(int64(limit))
+	return budget
+}
+
+// take резервирует один вызов и сообщает, остался ли на него бюджет
+func (b *callBudget) take() bool {
+	if !b.limited {
+		return true
+	}
+	return b.remaining.Add(-1) >= 0
+}
+
+// exhausted сообщает, что бюджет вызовов исчерпан
+func (b *callBudget) exhausted() bool {
+	return b.limited && b.remaining.Load() < 0
+}
+
+// fetchFeedItems получает элементы ленты одной подписки. Если limit не отрицателен, возвращается
+// не более limit элементов, а признак truncated сообщает, что у подписки есть еще элементы
+type fetchFeedItems[T any] func(ctx context.Context, subscribedToID uint, limit int) (items []T, truncated bool, err error)
+
+// fanOutResult — элементы ленты, собранные при обходе подписок
+type fanOutResult[T any] struct {
+	Items      []T
+	Truncated  bool // Обход остановлен по лимиту элементов или бюджету вызовов
+	Incomplete bool // Обход прерван по дедлайну контекста
+}
+
+// withFeedDeadline возвращает контекст, дедлайн которого наступает на margin раньше дедлайна ctx,
+// чтобы после прерывания обхода оставалось время вернуть собранную часть ленты
+func withFeedDeadline(ctx context.Context, margin time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || margin <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+// fanOutFeed обходит подписки группами по concurrency и собирает элементы ленты в порядке подписок.
+// Каждой группе выделяется остаток лимита maxItems, поэтому при concurrency 1 число вызовов
+// внешних сервисов совпадает с последовательным обходом.
+// Если дедлайн ctx наступает во время обхода, возвращаются элементы подписок, обработанных до первой незавершенной
+func fanOutFeed[T any](ctx context.Context, subscribedToIDs []uint, concurrency int, maxItems int, fetch fetchFeedItems[T]) (fanOutResult[T], error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	result := fanOutResult[T]{Items: make([]T, 0)}
+	for start := 0; start < len(subscribedToIDs); start += concurrency {
+		chunk := subscribedToIDs[start:min(start+concurrency, len(subscribedToIDs))]
+
+		limit := -1
+		if maxItems > 0 {
+			limit = maxItems - len(result.Items)
+		}
+
+		results := make([][]T, len(chunk))
+		truncatedResults := make([]bool, len(chunk))
+		completed := make([]bool, len(chunk))
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i, subscribedToID := range chunk {
+			group.Go(func() error {
+				items, truncated, err := fetch(groupCtx, subscribedToID, limit)
+				if err != nil {
+					return err
+				}
+				results[i] = items
+				truncatedResults[i] = truncated
+				completed[i] = true
+				return nil
+			})
+		}
+		err := group.Wait()
+		deadlineExceeded := err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+		if err != nil && !deadlineExceeded {
+			return fanOutResult[T]{}, err
+		}
+
+		for i := range chunk {
+			if !completed[i] {
+				result.Incomplete = true
+				return result, nil
+			}
+			for _, item := range results[i] {
+				if maxItems > 0 && len(result.Items) >= maxItems {
+					result.Truncated = true
+					return result, nil
+				}
+				result.Items = append(result.Items, item)
+			}
+			if truncatedResults[i] {
+				result.Truncated = true
+				return result, nil
+			}
+		}
+		if deadlineExceeded {
+			result.Incomplete = true
+			return result, nil
+		}
+		// Лимит заполнен ровно: следующие подписки не обходятся, лента обрезана, если они есть
+		if maxItems > 0 && len(result.Items) >= maxItems {
+			result.Truncated = start+len(chunk) < len(subscribedToIDs)
+			return result, nil
+		}
+	}
+
+	return result, nil
+}
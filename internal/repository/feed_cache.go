@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/watchlist-kata/subscription/internal/metrics"
+)
+
+// CachedFeedRepository кэширует страницы лент подписок и списки неактивных подписок на время ttl.
+// Ключ кэша включает пользователя, страницу и фильтры ленты. Ленты пользователей с большим числом подписок
+// могут храниться дольше (см. HeavyUserTTL). Кэш пользователя сбрасывается, когда меняются
+// его подписки через этот репозиторий; изменения, сделанные другими экземплярами сервиса, видны по истечении ttl.
+// Остальные методы передаются репозиторию без изменений
+type CachedFeedRepository struct {
+	SubscriptionRepository
+	watchlists *feedCache[WatchlistFeed]
+	reviews    *feedCache[ReviewFeed]
+	inactive   *feedCache[InactiveSubscriptions]
+	ttl        time.Duration
+	heavy      HeavyUserTTL
+}
+
+// HeavyUserTTL задает отдельное время хранения лент пользователей, подписанных не меньше чем на Threshold
+// пользователей: их ленты дороже всего строить, поэтому для них свежесть уступает стоимости.
+// Нулевое значение отключает переопределение
+type HeavyUserTTL struct {
+	Threshold int
+	TTL       time.Duration
+}
+
+// NewCachedFeedRepository создает новый экземпляр CachedFeedRepository поверх другого репозитория.
+// maxEntries ограничивает число страниц в каждом из кэшей (0 — без ограничения)
+func NewCachedFeedRepository(next SubscriptionRepository, ttl time.Duration, heavy HeavyUserTTL, maxEntries int) *CachedFeedRepository {
+	return &CachedFeedRepository{
+		SubscriptionRepository: next,
+		watchlists:             newFeedCache[WatchlistFeed]("feed_watchlists", ttl, maxEntries),
+		reviews:                newFeedCache[ReviewFeed]("feed_reviews", ttl, maxEntries),
+		inactive:               newFeedCache[InactiveSubscriptions]("inactive_subscriptions", ttl, maxEntries),
+		ttl:                    ttl,
+		heavy:                  heavy,
+	}
+}
+
+// ttlFor возвращает время хранения лент пользователя. Число подписок запрашивается только при промахе кэша;
+// если его не удалось получить, используется обычное время хранения
+func (r *CachedFeedRepository) ttlFor(ctx context.Context, userID uint) time.Duration {
+	if r.heavy.Threshold <= 0 || r.heavy.TTL <= 0 {
+		return r.ttl
+	}
+	count, err := r.SubscriptionRepository.CountSubscriptions(ctx, userID)
+	if err != nil || count < int64(r.heavy.Threshold) {
+		return r.ttl
+	}
+	return r.heavy.TTL
+}
+
+// GetWatchlistsBySubscription возвращает страницу ленты вотчлистов из кэша или строит ее.
+// При opts.ForceRefresh лента строится заново и заменяет закэшированную
+func (r *CachedFeedRepository) GetWatchlistsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*WatchlistFeed, error) {
+	key := feedCacheKey(opts)
+	if !opts.ForceRefresh {
+		if feed, ok := r.watchlists.get(userID, key); ok {
+			return &feed, nil
+		}
+	}
+
+	feed, err := r.SubscriptionRepository.GetWatchlistsBySubscription(ctx, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Лента, собранная не полностью из-за дедлайна, не кэшируется
+	if !feed.Incomplete {
+		r.watchlists.setWithTTL(userID, key, *feed, r.ttlFor(ctx, userID))
+	}
+	return feed, nil
+}
+
+// GetReviewsBySubscription возвращает страницу ленты отзывов из кэша или строит ее.
+// При opts.ForceRefresh лента строится заново и заменяет закэшированную
+func (r *CachedFeedRepository) GetReviewsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*ReviewFeed, error) {
+	key := feedCacheKey(opts)
+	if !opts.ForceRefresh {
+		if feed, ok := r.reviews.get(userID, key); ok {
+			return &feed, nil
+		}
+	}
+
+	feed, err := r.SubscriptionRepository.GetReviewsBySubscription(ctx, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Лента, собранная не полностью из-за дедлайна, не кэшируется
+	if !feed.Incomplete {
+		r.reviews.setWithTTL(userID, key, *feed, r.ttlFor(ctx, userID))
+	}
+	return feed, nil
+}
+
+// GetInactiveSubscriptions возвращает неактивные подписки из кэша или определяет их заново
+func (r *CachedFeedRepository) GetInactiveSubscriptions(ctx context.Context, userID uint, inactiveSince time.Time, opts FeedOptions) (*InactiveSubscriptions, error) {
+	key := strconv.FormatInt(inactiveSince.UnixNano(), 10)
+	if inactive, ok := r.inactive.get(userID, key); ok {
+		return &inactive, nil
+	}
+
+	inactive, err := r.SubscriptionRepository.GetInactiveSubscriptions(ctx, userID, inactiveSince, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Список, построенный не полностью из-за дедлайна, не кэшируется
+	if !inactive.Incomplete {
+		r.inactive.setWithTTL(userID, key, *inactive, r.ttlFor(ctx, userID))
+	}
+	return inactive, nil
+}
+
+// Subscribe добавляет подписку и сбрасывает кэш лент подписчика
+func (r *CachedFeedRepository) Subscribe(ctx context.Context, subscriberID uint, userID uint) (CreatedSubscription, error) {
+	created, err := r.SubscriptionRepository.Subscribe(ctx, subscriberID, userID)
+	if err == nil {
+		r.invalidate(subscriberID)
+	}
+	return created, err
+}
+
+// Unsubscribe удаляет подписку и сбрасывает кэш лент подписчика
+func (r *CachedFeedRepository) Unsubscribe(ctx context.Context, subscriberID uint, userID uint) error {
+	err := r.SubscriptionRepository.Unsubscribe(ctx, subscriberID, userID)
+	if err == nil {
+		r.invalidate(subscriberID)
+	}
+	return err
+}
+
+// UnsubscribeBatch удаляет подписки и сбрасывает кэш лент подписчика
+func (r *CachedFeedRepository) UnsubscribeBatch(ctx context.Context, subscriberID uint, userIDs []uint) ([]uint, error) {
+	removedIDs, err := r.SubscriptionRepository.UnsubscribeBatch(ctx, subscriberID, userIDs)
+	if err == nil && len(removedIDs) > 0 {
+		r.invalidate(subscriberID)
+	}
+	return removedIDs, err
+}
+
+// MergeUser переносит подписки и сбрасывает кэш лент обоих аккаунтов.
+// Ленты подписчиков объединяемого аккаунта обновятся по истечении ttl
+func (r *CachedFeedRepository) MergeUser(ctx context.Context, fromID uint, toID uint) (MergeResult, error) {
+	result, err := r.SubscriptionRepository.MergeUser(ctx, fromID, toID)
+	if err == nil {
+		r.invalidate(fromID)
+		r.invalidate(toID)
+	}
+	return result, err
+}
+
+// BulkSubscribeFollowersTo подписывает подписчиков на новый аккаунт и сбрасывает кэш лент подписанных пользователей
+func (r *CachedFeedRepository) BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint, batchSize int) ([]uint, error) {
+	subscribedIDs, err := r.SubscriptionRepository.BulkSubscribeFollowersTo(ctx, oldUserID, newUserID, followerIDs, batchSize)
+	// Часть пачек могла быть записана до ошибки
+	for _, subscribedID := range subscribedIDs {
+		r.invalidate(subscribedID)
+	}
+	return subscribedIDs, err
+}
+
+// invalidate сбрасывает все закэшированные страницы лент и неактивные подписки пользователя
+func (r *CachedFeedRepository) invalidate(userID uint) {
+	r.watchlists.invalidate(userID)
+	r.reviews.invalidate(userID)
+	r.inactive.invalidate(userID)
+}
+
+// feedCacheKey возвращает ключ страницы ленты с учетом фильтров, влияющих на ее содержимое
+func feedCacheKey(opts FeedOptions) string {
+	return fmt.Sprintf("%d:%d:%d:%t:%t:%s:%d/%d:%d:%d",
+		opts.Page.Limit, opts.Page.Offset, opts.MaxItems, opts.IncludeSelf, opts.Raw, opts.SortBy, opts.Shard.Index, opts.Shard.Count, opts.Since.UnixNano(), opts.MinRating)
+}
+
+// cachedFeed — страница ленты в кэше, срок ее хранения и момент его истечения
+type cachedFeed[T any] struct {
+	feed      T
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+// feedCache хранит страницы лент или другие записи кэша, сгруппированные по пользователям.
+// При maxEntries > 0 кэш хранит не больше maxEntries записей: когда места нет, сначала удаляются просроченные
+// записи, а если их нет — запись, срок хранения которой истекает раньше всех
+type feedCache[T any] struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[uint]map[string]cachedFeed[T]
+	size       int
+	lastSweep  time.Time
+}
+
+// newFeedCache создает новый экземпляр feedCache; name используется как имя кэша в метриках,
+// maxEntries ограничивает число записей (0 — без ограничения)
+func newFeedCache[T any](name string, ttl time.Duration, maxEntries int) *feedCache[T] {
+	return &feedCache[T]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[uint]map[string]cachedFeed[T]),
+	}
+}
+
+// get возвращает копию закэшированной страницы, если срок ее хранения не истек
+func (c *feedCache[T]) get(userID uint, key string) (T, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userID][key]
+	if ok && now.Before(entry.expiresAt) {
+		metrics.RecordCacheHit(c.name, ttlUsed(entry.expiresAt, now, entry.ttl))
+		return entry.feed, true
+	}
+	if ok {
+		c.deleteLocked(userID, key)
+		metrics.RecordCacheEvictions(c.name, metrics.EvictionExpired, 1)
+	}
+	metrics.RecordCacheMiss(c.name)
+	var zero T
+	return zero, false
+}
+
+// set сохраняет страницу ленты на время хранения кэша
+func (c *feedCache[T]) set(userID uint, key string, feed T) {
+	c.setWithTTL(userID, key, feed, c.ttl)
+}
+
+// setWithTTL сохраняет страницу ленты на время ttl. Не чаще раза в ttl кэша из него удаляются все просроченные страницы
+func (c *feedCache[T]) setWithTTL(userID uint, key string, feed T, ttl time.Duration) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		c.sweepLocked(now)
+		c.lastSweep = now
+	}
+	_, exists := c.entries[userID][key]
+	if !exists && c.maxEntries > 0 && c.size >= c.maxEntries {
+		c.sweepLocked(now)
+		c.lastSweep = now
+		if c.size >= c.maxEntries {
+			c.evictSoonestLocked()
+		}
+	}
+	if c.entries[userID] == nil {
+		c.entries[userID] = make(map[string]cachedFeed[T])
+	}
+	if !exists {
+		c.size++
+	}
+	c.entries[userID][key] = cachedFeed[T]{feed: feed, ttl: ttl, expiresAt: now.Add(ttl)}
+}
+
+// evictSoonestLocked удаляет запись, срок хранения которой истекает раньше всех; вызывается под c.mu
+func (c *feedCache[T]) evictSoonestLocked() {
+	var (
+		found     bool
+		oldestID  uint
+		oldestKey string
+		oldestAt  time.Time
+	)
+	for userID, pages := range c.entries {
+		for key, entry := range pages {
+			if !found || entry.expiresAt.Before(oldestAt) {
+				found, oldestID, oldestKey, oldestAt = true, userID, key, entry.expiresAt
+			}
+		}
+	}
+	if found {
+		c.deleteLocked(oldestID, oldestKey)
+		metrics.RecordCacheEvictions(c.name, metrics.EvictionCapacity, 1)
+	}
+}
+
+// deleteLocked удаляет запись; вызывается под c.mu
+func (c *feedCache[T]) deleteLocked(userID uint, key string) {
+	pages := c.entries[userID]
+	if _, ok := pages[key]; !ok {
+		return
+	}
+	delete(pages, key)
+	c.size--
+	if len(pages) == 0 {
+		delete(c.entries, userID)
+	}
+}
+
+// invalidate удаляет все страницы лент пользователя
+func (c *feedCache[T]) invalidate(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metrics.RecordCacheEvictions(c.name, metrics.EvictionInvalidated, len(c.entries[userID]))
+	c.size -= len(c.entries[userID])
+	delete(c.entries, userID)
+}
+
+// sweepLocked удаляет просроченные страницы; вызывается под c.mu
+func (c *feedCache[T]) sweepLocked(now time.Time) {
+	expired := 0
+	for userID, pages := range c.entries {
+		for key, entry := range pages {
+			if !now.Before(entry.expiresAt) {
+				delete(pages, key)
+				c.size--
+				expired++
+			}
+		}
+		if len(pages) == 0 {
+			delete(c.entries, userID)
+		}
+	}
+	metrics.RecordCacheEvictions(c.name, metrics.EvictionExpired, expired)
+}
+
+// ttlUsed возвращает долю срока хранения ttl записи, истекающей в expiresAt, прошедшую к моменту now
+func ttlUsed(expiresAt time.Time, now time.Time, ttl time.Duration) float64 {
+	return 1 - float64(expiresAt.Sub(now))/float64(ttl)
+}
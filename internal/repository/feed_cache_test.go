@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedCacheEvictsSoonestExpiringEntryWhenFull(t *testing.T) {
+	c := newFeedCache[int]("test_bounded", time.Minute, 2)
+	c.setWithTTL(1, "a", 1, time.Hour)
+	c.setWithTTL(2, "a", 2, time.Second)
+	c.setWithTTL(3, "a", 3, time.Hour)
+
+	if _, ok := c.get(2, "a"); ok {
+		t.Error("entry with the soonest expiry was not evicted")
+	}
+	for _, userID := range []uint{1, 3} {
+		if _, ok := c.get(userID, "a"); !ok {
+			t.Errorf("entry of user %d was evicted", userID)
+		}
+	}
+	if c.size != 2 {
+		t.Errorf("cache holds %d entries, want 2", c.size)
+	}
+}
+
+func TestFeedCacheOverwriteDoesNotEvict(t *testing.T) {
+	c := newFeedCache[int]("test_bounded", time.Minute, 2)
+	c.set(1, "a", 1)
+	c.set(2, "a", 2)
+	c.set(2, "a", 3)
+
+	if feed, ok := c.get(1, "a"); !ok || feed != 1 {
+		t.Errorf("got (%d, %t) for user 1, want the original entry", feed, ok)
+	}
+	if feed, ok := c.get(2, "a"); !ok || feed != 3 {
+		t.Errorf("got (%d, %t) for user 2, want the overwritten entry", feed, ok)
+	}
+}
+
+func TestFeedCacheInvalidateFreesCapacity(t *testing.T) {
+	c := newFeedCache[int]("test_bounded", time.Minute, 2)
+	c.set(1, "a", 1)
+	c.set(1, "b", 2)
+	c.invalidate(1)
+	c.set(2, "a", 3)
+	c.set(3, "a", 4)
+
+	if c.size != 2 {
+		t.Errorf("cache holds %d entries, want 2", c.size)
+	}
+	for _, userID := range []uint{2, 3} {
+		if _, ok := c.get(userID, "a"); !ok {
+			t.Errorf("entry of user %d was evicted", userID)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// SingleflightFeedRepository объединяет одновременные одинаковые запросы лент: пока лента строится,
+// запросы с тем же пользователем, страницей и фильтрами ждут ее результата вместо повторных вызовов внешних сервисов.
+// Лента строится без отмены первого запроса, но с его дедлайном: отмена любого запроса прекращает только его ожидание.
+// Остальные методы передаются репозиторию без изменений
+type SingleflightFeedRepository struct {
+	SubscriptionRepository
+	watchlists singleflight.Group
+	reviews    singleflight.Group
+}
+
+// NewSingleflightFeedRepository создает новый экземпляр SingleflightFeedRepository поверх другого репозитория
+func NewSingleflightFeedRepository(next SubscriptionRepository) *SingleflightFeedRepository {
+	return &SingleflightFeedRepository{SubscriptionRepository: next}
+}
+
+// GetWatchlistsBySubscription возвращает ленту вотчлистов, присоединяясь к уже идущему построению такой же ленты
+func (r *SingleflightFeedRepository) GetWatchlistsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*WatchlistFeed, error) {
+	return joinFeedBuild(ctx, &r.watchlists, singleflightKey(userID, opts), func(ctx context.Context) (*WatchlistFeed, error) {
+		return r.SubscriptionRepository.GetWatchlistsBySubscription(ctx, userID, opts)
+	})
+}
+
+// GetReviewsBySubscription возвращает ленту отзывов, присоединяясь к уже идущему построению такой же ленты
+func (r *SingleflightFeedRepository) GetReviewsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*ReviewFeed, error) {
+	return joinFeedBuild(ctx, &r.reviews, singleflightKey(userID, opts), func(ctx context.Context) (*ReviewFeed, error) {
+		return r.SubscriptionRepository.GetReviewsBySubscription(ctx, userID, opts)
+	})
+}
+
+// joinFeedBuild ждет результата построения ленты по ключу key, запуская build, если такая лента еще не строится.
+// Построение выполняется с контекстом без отмены и с дедлайном запроса, который его запустил,
+// поэтому обход подписок по-прежнему успевает вернуть частичную ленту к этому дедлайну
+func joinFeedBuild[T any](ctx context.Context, group *singleflight.Group, key string, build func(ctx context.Context) (*T, error)) (*T, error) {
+	results := group.DoChan(key, func() (any, error) {
+		buildCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			buildCtx, cancel = context.WithDeadline(buildCtx, deadline)
+			defer cancel()
+		}
+		return build(buildCtx)
+	})
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		// Каждый запрос получает свою копию ленты, элементы остаются общими
+		feed := *result.Val.(*T)
+		return &feed, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// singleflightKey возвращает ключ построения ленты. Запрос с ForceRefresh не присоединяется к построению,
+// которое может вернуть ленту из кэша
+func singleflightKey(userID uint, opts FeedOptions) string {
+	return fmt.Sprintf("%d:%s:%t", userID, feedCacheKey(opts), opts.ForceRefresh)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingFeedRepository строит ленту вотчлистов только после закрытия release
+// и завершается ошибкой контекста, если контекст построения отменен раньше
+type blockingFeedRepository struct {
+	SubscriptionRepository
+	started chan struct{}
+	release chan struct{}
+	builds  atomic.Int32
+}
+
+func (r *blockingFeedRepository) GetWatchlistsBySubscription(ctx context.Context, _ uint, _ FeedOptions) (*WatchlistFeed, error) {
+	if r.builds.Add(1) == 1 {
+		close(r.started)
+	}
+	select {
+	case <-r.release:
+		return &WatchlistFeed{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestSingleflightCancellationDoesNotFailJoinedRequest(t *testing.T) {
+	next := &blockingFeedRepository{started: make(chan struct{}), release: make(chan struct{})}
+	r := NewSingleflightFeedRepository(next)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := r.GetWatchlistsBySubscription(firstCtx, 1, FeedOptions{})
+		firstErr <- err
+	}()
+	<-next.started
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := r.GetWatchlistsBySubscription(context.Background(), 1, FeedOptions{})
+		secondErr <- err
+	}()
+	// singleflight не сообщает о присоединении, поэтому второму запросу дается время присоединиться
+	time.Sleep(50 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first request returned %v, want context.Canceled", err)
+	}
+
+	close(next.release)
+	if err := <-secondErr; err != nil {
+		t.Fatalf("joined request returned %v, want the shared feed", err)
+	}
+	if builds := next.builds.Load(); builds != 1 {
+		t.Errorf("feed built %d times, want 1", builds)
+	}
+}
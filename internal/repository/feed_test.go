@@ -0,0 +1,304 @@
+package repository_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/watchlist-kata/protos/media"
+	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/protos/user"
+	"github.com/watchlist-kata/protos/watchlist"
+
+	"github.com/watchlist-kata/subscription/internal/repository"
+	"github.com/watchlist-kata/subscription/internal/testutil"
+)
+
+// feedFixture — репозиторий поверх in-memory подписок и фейковых внешних сервисов
+type feedFixture struct {
+	repo          *repository.PostgresSubscriptionRepository
+	downstream    *testutil.Downstream
+	subscriptions *testutil.SubscriptionDB
+}
+
+// newFeedFixture создает репозиторий с пустыми подписками и внешними сервисами
+func newFeedFixture(t *testing.T) *feedFixture {
+	t.Helper()
+
+	downstream, err := testutil.StartDownstream()
+	if err != nil {
+		t.Fatalf("start downstream: %v", err)
+	}
+	t.Cleanup(downstream.Close)
+
+	subscriptions := testutil.NewSubscriptionDB()
+	db, err := subscriptions.Gorm()
+	if err != nil {
+		t.Fatalf("open subscription db: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn := downstream.Conn
+	repo := repository.NewPostgresSubscriptionRepositoryFromConns(db, logger, conn, conn, conn, conn, repository.Options{})
+	return &feedFixture{repo: repo, downstream: downstream, subscriptions: subscriptions}
+}
+
+// addUser добавляет пользователя с именем username
+func (f *feedFixture) addUser(id int64, username string) {
+	f.downstream.User.Add(&user.User{Id: id, Username: username})
+}
+
+// addMedia добавляет медиа с названием name
+func (f *feedFixture) addMedia(id int64, name string) {
+	f.downstream.Media.Add(&media.Media{Id: id, NameEn: name})
+}
+
+func TestGetReviewsBySubscriptionFetchesEachMediaOnce(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2, 3)
+	f.addUser(2, "alice")
+	f.addUser(3, "bob")
+	f.addMedia(10, "Alien")
+	f.addMedia(11, "Heat")
+	f.downstream.Review.Add(&review.Review{Id: 100, UserId: 2, MediaId: 10, CreatedAt: "2025-01-03T00:00:00Z"})
+	f.downstream.Review.Add(&review.Review{Id: 101, UserId: 2, MediaId: 11, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Review.Add(&review.Review{Id: 102, UserId: 3, MediaId: 10, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	feed, err := f.repo.GetReviewsBySubscription(context.Background(), 1, repository.FeedOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 3 {
+		t.Fatalf("got %d items, want 3", len(feed.Items))
+	}
+	if feed.Truncated || feed.Incomplete {
+		t.Errorf("got truncated=%t incomplete=%t, want a complete feed", feed.Truncated, feed.Incomplete)
+	}
+	if got := f.downstream.Media.Calls("GetMediaByID"); got != 2 {
+		t.Errorf("GetMediaByID called %d times, want once per distinct media (2)", got)
+	}
+	if got := f.downstream.User.Calls("GetByID"); got != 2 {
+		t.Errorf("GetByID called %d times, want once per subscription (2)", got)
+	}
+	if got := f.downstream.Review.Calls("GetByUser"); got != 2 {
+		t.Errorf("GetByUser called %d times, want once per subscription (2)", got)
+	}
+}
+
+func TestGetWatchlistsBySubscriptionResolvesUsernameOncePerSubscription(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2)
+	f.addUser(2, "alice")
+	f.addMedia(10, "Alien")
+	f.addMedia(11, "Heat")
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 11, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	feed, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Items))
+	}
+	for _, item := range feed.Items {
+		if item.UserName != "alice" {
+			t.Errorf("item for media %d has username %q, want alice", item.MediaId, item.UserName)
+		}
+	}
+	if got := f.downstream.User.Calls("GetByID"); got != 1 {
+		t.Errorf("GetByID called %d times, want 1", got)
+	}
+}
+
+func TestGetWatchlistsBySubscriptionStopsAtMaxItems(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2, 3, 4)
+	for userID := int64(2); userID <= 4; userID++ {
+		f.addUser(userID, "user")
+		f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: userID, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+		f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: userID, MediaId: 11, CreatedAt: "2025-01-01T00:00:00Z"})
+	}
+	f.addMedia(10, "Alien")
+	f.addMedia(11, "Heat")
+
+	feed, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{MaxItems: 3, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 3 {
+		t.Fatalf("got %d items, want 3", len(feed.Items))
+	}
+	if !feed.Truncated {
+		t.Error("feed is not marked truncated")
+	}
+	if got := f.downstream.Watchlist.Calls("GetWatchlist"); got != 2 {
+		t.Errorf("GetWatchlist called %d times, want 2: fan-out must stop once the cap is reached", got)
+	}
+}
+
+func TestGetWatchlistsBySubscriptionReturnsPartialFeedWhenCallBudgetIsExhausted(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2, 3)
+	f.addUser(2, "alice")
+	f.addUser(3, "bob")
+	f.addMedia(10, "Alien")
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 3, MediaId: 10, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	// Первая подписка расходует три вызова: вотчлист, медиа и имя; на вторую остается только вотчлист
+	feed, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{CallBudget: 4, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 1 || feed.Items[0].UserId != 2 {
+		t.Fatalf("got items %v, want only the first subscription's item", feed.Items)
+	}
+	if !feed.Truncated {
+		t.Error("feed is not marked truncated")
+	}
+	if got := f.downstream.Media.Calls("GetMediaByID"); got != 1 {
+		t.Errorf("GetMediaByID called %d times, want 1: calls beyond the budget must not be made", got)
+	}
+}
+
+func TestGetReviewsBySubscriptionPagesWithinMaxItems(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2)
+	f.addUser(2, "alice")
+	f.addMedia(10, "Alien")
+	f.downstream.Review.Add(&review.Review{Id: 100, UserId: 2, MediaId: 10, CreatedAt: "2025-01-03T00:00:00Z"})
+	f.downstream.Review.Add(&review.Review{Id: 101, UserId: 2, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Review.Add(&review.Review{Id: 102, UserId: 2, MediaId: 10, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	feed, err := f.repo.GetReviewsBySubscription(context.Background(), 1, repository.FeedOptions{
+		MaxItems: 2,
+		Page:     repository.Page{Limit: 1, Offset: 1},
+	})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 1 || feed.Items[0].ReviewId != 101 {
+		t.Fatalf("got items %v, want the second newest review", feed.Items)
+	}
+	if !feed.Truncated {
+		t.Error("feed is not marked truncated")
+	}
+}
+
+func TestGetWatchlistsBySubscriptionSkipsRemainingSubscriptionsWhenCapIsFilledExactly(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2, 3, 4)
+	f.addUser(2, "alice")
+	f.addMedia(10, "Alien")
+	f.addMedia(11, "Heat")
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 11, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	feed, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{MaxItems: 2, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Items))
+	}
+	if !feed.Truncated {
+		t.Error("feed is not marked truncated although subscriptions remain")
+	}
+	if got := f.downstream.Watchlist.Calls("GetWatchlist"); got != 1 {
+		t.Errorf("GetWatchlist called %d times, want 1", got)
+	}
+}
+
+func TestGetWatchlistsBySubscriptionIsNotTruncatedWhenLastSubscriptionFillsCap(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 2)
+	f.addUser(2, "alice")
+	f.addMedia(10, "Alien")
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: "2025-01-02T00:00:00Z"})
+
+	feed, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{MaxItems: 1, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+
+	if len(feed.Items) != 1 || feed.Truncated {
+		t.Errorf("got %d items, truncated=%t; want 1 item, not truncated", len(feed.Items), feed.Truncated)
+	}
+}
+
+func TestFeedsWithoutSubscriptionsAreEmptyButNotNil(t *testing.T) {
+	f := newFeedFixture(t)
+
+	watchlists, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+	if watchlists.Items == nil || len(watchlists.Items) != 0 {
+		t.Errorf("got watchlist items %#v, want an empty non-nil slice", watchlists.Items)
+	}
+
+	reviews, err := f.repo.GetReviewsBySubscription(context.Background(), 1, repository.FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+	if reviews.Items == nil || len(reviews.Items) != 0 {
+		t.Errorf("got review items %#v, want an empty non-nil slice", reviews.Items)
+	}
+
+	if got := f.downstream.Watchlist.Calls("GetWatchlist") + f.downstream.Review.Calls("GetByUser"); got != 0 {
+		t.Errorf("downstream services called %d times, want none", got)
+	}
+}
+
+func TestFeedsBreakTimestampTiesByUserAndMedia(t *testing.T) {
+	f := newFeedFixture(t)
+	f.subscriptions.Subscribe(1, 3, 2)
+	f.addUser(2, "alice")
+	f.addUser(3, "bob")
+	f.addMedia(10, "Alien")
+	f.addMedia(11, "Heat")
+	const same = "2025-01-01T00:00:00Z"
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 3, MediaId: 10, CreatedAt: same})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 11, CreatedAt: same})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: same})
+	f.downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 3, MediaId: 11, CreatedAt: "2025-01-02T00:00:00Z"})
+	f.downstream.Review.Add(&review.Review{Id: 103, UserId: 3, MediaId: 10, CreatedAt: same})
+	f.downstream.Review.Add(&review.Review{Id: 102, UserId: 2, MediaId: 11, CreatedAt: same})
+	f.downstream.Review.Add(&review.Review{Id: 101, UserId: 2, MediaId: 10, CreatedAt: same})
+
+	watchlists, err := f.repo.GetWatchlistsBySubscription(context.Background(), 1, repository.FeedOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+	wantWatchlists := [][2]int64{{3, 11}, {2, 10}, {2, 11}, {3, 10}}
+	if len(watchlists.Items) != len(wantWatchlists) {
+		t.Fatalf("got %d watchlist items, want %d", len(watchlists.Items), len(wantWatchlists))
+	}
+	for i, want := range wantWatchlists {
+		if got := [2]int64{watchlists.Items[i].UserId, watchlists.Items[i].MediaId}; got != want {
+			t.Errorf("watchlist item %d is (user, media) %v, want %v", i, got, want)
+		}
+	}
+
+	reviews, err := f.repo.GetReviewsBySubscription(context.Background(), 1, repository.FeedOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+	wantReviews := []int64{101, 102, 103}
+	if len(reviews.Items) != len(wantReviews) {
+		t.Fatalf("got %d reviews, want %d", len(reviews.Items), len(wantReviews))
+	}
+	for i, want := range wantReviews {
+		if got := reviews.Items[i].ReviewId; got != want {
+			t.Errorf("review %d is %d, want %d", i, got, want)
+		}
+	}
+}
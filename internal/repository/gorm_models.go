@@ -2,15 +2,19 @@ package repository
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// GormSubscription представляет модель подписки в базе данных
+// GormSubscription представляет модель подписки в базе данных.
+// Удаление подписки мягкое: заполняется DeletedAt, а строки окончательно удаляются задачей очистки
 type GormSubscription struct {
 	ID           uint `gorm:"primaryKey"`
-	SubscriberID uint `gorm:"column:subscriber_id"`
-	UserID       uint `gorm:"column:user_id"`
+	SubscriberID uint `gorm:"column:subscriber_id;uniqueIndex:idx_subscription_subscriber_user,where:deleted_at IS NULL"`
+	UserID       uint `gorm:"column:user_id;uniqueIndex:idx_subscription_subscriber_user,where:deleted_at IS NULL"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
 // TableName возвращает имя таблицы для модели GormSubscription
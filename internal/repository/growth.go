@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// GrowthBucket задает размер интервала, по которым группируются новые подписчики
+type GrowthBucket string
+
+// Размеры интервалов статистики прироста подписчиков
+const (
+	// GrowthByDay группирует подписчиков по суткам (UTC)
+	GrowthByDay GrowthBucket = "day"
+	// GrowthByWeek группирует подписчиков по неделям, начиная с понедельника (UTC)
+	GrowthByWeek GrowthBucket = "week"
+)
+
+// ParseGrowthBucket проверяет и возвращает размер интервала статистики
+func ParseGrowthBucket(value string) (GrowthBucket, error) {
+	switch bucket := GrowthBucket(value); bucket {
+	case GrowthByDay, GrowthByWeek:
+		return bucket, nil
+	default:
+		return "", fmt.Errorf("unknown growth bucket: %q", value)
+	}
+}
+
+// Duration возвращает длительность интервала
+func (b GrowthBucket) Duration() time.Duration {
+	if b == GrowthByWeek {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// GrowthPoint — число новых подписчиков за один интервал
+type GrowthPoint struct {
+	BucketStart time.Time // Начало интервала (UTC)
+	Count       int64
+}
+
+// GetSubscriptionGrowth возвращает число новых действующих подписчиков пользователя, подписавшихся в [from, to),
+// по интервалам bucket в порядке возрастания. Интервалы без новых подписчиков не возвращаются
+func (r *PostgresSubscriptionRepository) GetSubscriptionGrowth(ctx context.Context, userID uint, from time.Time, to time.Time, bucket GrowthBucket) ([]GrowthPoint, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscriptionGrowth operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var rows []struct {
+		BucketStart time.Time
+		Count       int64
+	}
+	if err := r.db.Raw(
+		`SELECT date_trunc(?, created_at AT TIME ZONE 'UTC') AS bucket_start, COUNT(*) AS count
+		FROM subscription
+		WHERE user_id = ? AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		GROUP BY bucket_start
+		ORDER BY bucket_start`,
+		string(bucket), userID, from, to,
+	).Scan(&rows).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscription growth", slog.Any("error", err))
+		return nil, err
+	}
+
+	points := make([]GrowthPoint, 0, len(rows))
+	for _, row := range rows {
+		points = append(points, GrowthPoint{BucketStart: row.BucketStart.UTC(), Count: row.Count})
+	}
+
+	r.logger.InfoContext(ctx, "subscription growth fetched successfully", slog.Int("buckets", len(points)))
+	return points, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// dependencyFailureThreshold — число подряд идущих сбоев, после которого сервис считается недоступным
+	dependencyFailureThreshold = 3
+	// dependencyCooldown — время, в течение которого вызовы недоступного сервиса не выполняются
+	dependencyCooldown = 10 * time.Second
+)
+
+// ErrDependencyUnavailable возвращается, когда внешний сервис недавно был недоступен и вызов к нему пропущен
+var ErrDependencyUnavailable = errors.New("dependency is unavailable")
+
+// HealthReporter сообщает о состоянии внешних сервисов
+type HealthReporter interface {
+	DependencyHealth() map[string]bool
+}
+
+// dependencyHealth отслеживает недавние сбои вызовов внешнего сервиса
+type dependencyHealth struct {
+	name                string
+	mu                  sync.Mutex
+	consecutiveFailures int
+	downUntil           time.Time
+}
+
+// newDependencyHealth создает новый экземпляр dependencyHealth
+func newDependencyHealth(name string) *dependencyHealth {
+	return &dependencyHealth{name: name}
+}
+
+// Allow возвращает ошибку, если сервис считается недоступным.
+// По истечении паузы пропускается пробный вызов, результат которого определяет дальнейшее состояние
+func (h *dependencyHealth) Allow() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if now := time.Now(); now.Before(h.downUntil) {
+		return &DownstreamError{Service: h.name, Err: ErrDependencyUnavailable, RetryAfter: h.downUntil.Sub(now)}
+	}
+	return nil
+}
+
+// Record учитывает результат вызова сервиса
+func (h *dependencyHealth) Record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !isDependencyFailure(err) {
+		h.consecutiveFailures = 0
+		h.downUntil = time.Time{}
+		return
+	}
+	h.consecutiveFailures++
+	if h.consecutiveFailures >= dependencyFailureThreshold {
+		h.downUntil = time.Now().Add(dependencyCooldown)
+	}
+}
+
+// Healthy сообщает, считается ли сервис доступным
+func (h *dependencyHealth) Healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.consecutiveFailures < dependencyFailureThreshold
+}
+
+// isDependencyFailure определяет, указывает ли ошибка на недоступность сервиса, а не на ошибку в запросе
+func isDependencyFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
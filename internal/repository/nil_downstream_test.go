@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/watchlist-kata/protos/media"
+	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/protos/user"
+	"github.com/watchlist-kata/protos/watchlist"
+
+	"github.com/watchlist-kata/subscription/internal/testutil"
+)
+
+// nilMediaClient возвращает пустой ответ вместо медиа
+type nilMediaClient struct {
+	media.MediaServiceClient
+}
+
+func (nilMediaClient) GetMediaByID(context.Context, *media.GetMediaByIDRequest, ...grpc.CallOption) (*media.Media, error) {
+	return nil, nil
+}
+
+// nilUserClient возвращает ответ без пользователя
+type nilUserClient struct {
+	user.UserServiceClient
+}
+
+func (nilUserClient) GetByID(context.Context, *user.GetUserRequest, ...grpc.CallOption) (*user.GetUserResponse, error) {
+	return &user.GetUserResponse{}, nil
+}
+
+// nilEntriesWatchlistClient добавляет пустые элементы в ответ сервиса вотчлистов
+type nilEntriesWatchlistClient struct {
+	watchlist.WatchlistServiceClient
+}
+
+func (c nilEntriesWatchlistClient) GetWatchlist(ctx context.Context, in *watchlist.GetWatchlistRequest, opts ...grpc.CallOption) (*watchlist.GetWatchlistResponse, error) {
+	response, err := c.WatchlistServiceClient.GetWatchlist(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	response.Watchlists = append([]*watchlist.WatchlistItem{nil}, append(response.Watchlists, nil)...)
+	return response, nil
+}
+
+// nilEntriesReviewClient добавляет пустые элементы в ответ сервиса отзывов
+type nilEntriesReviewClient struct {
+	review.ReviewServiceClient
+}
+
+func (c nilEntriesReviewClient) GetByUser(ctx context.Context, in *review.GetByUserRequest, opts ...grpc.CallOption) (*review.GetByUserResponse, error) {
+	response, err := c.ReviewServiceClient.GetByUser(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	response.Reviews = append([]*review.Review{nil}, append(response.Reviews, nil)...)
+	return response, nil
+}
+
+// newNilDownstreamRepository создает репозиторий, в котором пользователь 1 подписан на пользователя 2,
+// а у пользователя 2 есть отзыв и элемент вотчлиста на медиа 10
+func newNilDownstreamRepository(t *testing.T) *PostgresSubscriptionRepository {
+	t.Helper()
+
+	downstream, err := testutil.StartDownstream()
+	if err != nil {
+		t.Fatalf("start downstream: %v", err)
+	}
+	t.Cleanup(downstream.Close)
+	downstream.User.Add(&user.User{Id: 2, Username: "alice"})
+	downstream.Media.Add(&media.Media{Id: 10, NameEn: "Alien"})
+	downstream.Review.Add(&review.Review{Id: 100, UserId: 2, MediaId: 10, CreatedAt: "2025-01-01T00:00:00Z"})
+	downstream.Watchlist.Add(&watchlist.WatchlistItem{UserId: 2, MediaId: 10, CreatedAt: "2025-01-01T00:00:00Z"})
+
+	subscriptions := testutil.NewSubscriptionDB()
+	subscriptions.Subscribe(1, 2)
+	db, err := subscriptions.Gorm()
+	if err != nil {
+		t.Fatalf("open subscription db: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn := downstream.Conn
+	return NewPostgresSubscriptionRepositoryFromConns(db, logger, conn, conn, conn, conn, Options{})
+}
+
+func TestFeedsTolerateNilMedia(t *testing.T) {
+	r := newNilDownstreamRepository(t)
+	r.mediaClient = nilMediaClient{r.mediaClient}
+
+	watchlists, err := r.GetWatchlistsBySubscription(context.Background(), 1, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+	if len(watchlists.Items) != 1 || watchlists.Items[0].Title != "" || watchlists.Items[0].MediaId != 10 {
+		t.Errorf("got watchlist items %v, want one item for media 10 without a title", watchlists.Items)
+	}
+
+	reviews, err := r.GetReviewsBySubscription(context.Background(), 1, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+	if len(reviews.Items) != 1 || reviews.Items[0].MediaName != "" {
+		t.Errorf("got review items %v, want one review without a media name", reviews.Items)
+	}
+}
+
+func TestFeedsTolerateNilUser(t *testing.T) {
+	r := newNilDownstreamRepository(t)
+	r.userClient = nilUserClient{r.userClient}
+	r.usernames = newDirectUsernameResolver(r.userClient, r.userHealth, r.logger)
+
+	watchlists, err := r.GetWatchlistsBySubscription(context.Background(), 1, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+	if len(watchlists.Items) != 1 || watchlists.Items[0].UserName != "" {
+		t.Errorf("got watchlist items %v, want one item without a username", watchlists.Items)
+	}
+
+	reviews, err := r.GetReviewsBySubscription(context.Background(), 1, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+	if len(reviews.Items) != 1 || reviews.Items[0].UserName != "" {
+		t.Errorf("got review items %v, want one review without a username", reviews.Items)
+	}
+}
+
+func TestFeedsSkipNilListEntries(t *testing.T) {
+	r := newNilDownstreamRepository(t)
+	r.watchlistClient = nilEntriesWatchlistClient{r.watchlistClient}
+	r.reviewClient = nilEntriesReviewClient{r.reviewClient}
+
+	watchlists, err := r.GetWatchlistsBySubscription(context.Background(), 1, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetWatchlistsBySubscription: %v", err)
+	}
+	if len(watchlists.Items) != 1 || watchlists.Items[0].MediaId != 10 {
+		t.Errorf("got watchlist items %v, want only the non-nil item", watchlists.Items)
+	}
+
+	reviews, err := r.GetReviewsBySubscription(context.Background(), 1, FeedOptions{Since: time.Unix(0, 0)})
+	if err != nil {
+		t.Fatalf("GetReviewsBySubscription: %v", err)
+	}
+	if len(reviews.Items) != 1 || reviews.Items[0].ReviewId != 100 {
+		t.Errorf("got review items %v, want only the non-nil review", reviews.Items)
+	}
+
+	active, err := r.GetActiveSubscriptions(context.Background(), 1, 0, FeedOptions{})
+	if err != nil {
+		t.Fatalf("GetActiveSubscriptions: %v", err)
+	}
+	if len(active.Items) != 1 || active.Items[0].UserID != 2 {
+		t.Errorf("got active subscriptions %v, want user 2", active.Items)
+	}
+}
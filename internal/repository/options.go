@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Options содержит настройки поведения репозитория
+type Options struct {
+	// UsernameCacheTTL задает время кэширования имен пользователей (0 — без кэша)
+	UsernameCacheTTL time.Duration
+	// UsernameNormalization задает преобразование имен пользователей в элементах ленты (пусто — без изменений)
+	UsernameNormalization UsernameNormalization
+	// GraphQueryTimeout ограничивает время выполнения тяжелых запросов по графу подписок (0 — без ограничения)
+	GraphQueryTimeout time.Duration
+	// GraphMaxFanout ограничивает число подписок пользователя, учитываемых в запросах второго уровня
+	// (взаимные подписчики, общие подписки, подписки набора пользователей);
+	// сверх лимита берутся подписки с наименьшими ID (0 — без ограничения)
+	GraphMaxFanout int
+	// StartupCheckTimeout включает проверку доступности внешних сервисов при создании репозитория
+	// и ограничивает время ожидания каждого сервиса (0 — без проверки)
+	StartupCheckTimeout time.Duration
+}
+
+// graphFanoutLimit возвращает значение LIMIT для выборки подписок пользователя в запросах второго уровня.
+// NULL в LIMIT Postgres понимает как отсутствие ограничения
+func (r *PostgresSubscriptionRepository) graphFanoutLimit() any {
+	if r.options.GraphMaxFanout <= 0 {
+		return nil
+	}
+	return r.options.GraphMaxFanout
+}
+
+// capGraphFanout оставляет не более GraphMaxFanout различных пользователей с наименьшими ID
+func (r *PostgresSubscriptionRepository) capGraphFanout(userIDs []uint) []uint {
+	if r.options.GraphMaxFanout <= 0 || len(userIDs) <= r.options.GraphMaxFanout {
+		return userIDs
+	}
+	capped := slices.Clone(userIDs)
+	slices.Sort(capped)
+	capped = slices.Compact(capped)
+	return capped[:min(len(capped), r.options.GraphMaxFanout)]
+}
+
+// withGraphQueryTimeout выполняет запрос по графу подписок в транзакции с statement_timeout,
+// чтобы Postgres прервал запрос, выполняющийся дольше GraphQueryTimeout
+func (r *PostgresSubscriptionRepository) withGraphQueryTimeout(query func(tx *gorm.DB) error) error {
+	if r.options.GraphQueryTimeout <= 0 {
+		return query(r.db)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// SET LOCAL не поддерживает параметры запроса, значение подставляется как целое число миллисекунд
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", r.options.GraphQueryTimeout.Milliseconds())).Error; err != nil {
+			return err
+		}
+		return query(tx)
+	})
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCapGraphFanoutKeepsLowestDistinctIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxFanout int
+		userIDs   []uint
+		want      []uint
+	}{
+		{name: "unlimited", maxFanout: 0, userIDs: []uint{5, 3, 9}, want: []uint{5, 3, 9}},
+		{name: "under cap", maxFanout: 3, userIDs: []uint{5, 3, 9}, want: []uint{5, 3, 9}},
+		{name: "over cap", maxFanout: 2, userIDs: []uint{5, 3, 9, 1}, want: []uint{1, 3}},
+		{name: "duplicates", maxFanout: 2, userIDs: []uint{5, 5, 5, 3}, want: []uint{3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &PostgresSubscriptionRepository{options: Options{GraphMaxFanout: tt.maxFanout}}
+			if got := r.capGraphFanout(tt.userIDs); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphFanoutLimitIsNullWhenUnlimited(t *testing.T) {
+	r := &PostgresSubscriptionRepository{}
+	if limit := r.graphFanoutLimit(); limit != nil {
+		t.Errorf("got LIMIT %v, want NULL", limit)
+	}
+	r.options.GraphMaxFanout = 10
+	if limit := r.graphFanoutLimit(); limit != 10 {
+		t.Errorf("got LIMIT %v, want 10", limit)
+	}
+}
@@ -0,0 +1,26 @@
+package repository
+
+import "time"
+
+// Page задает параметры постраничной выборки
+type Page struct {
+	Limit  int // Максимальное число записей на странице
+	Offset int // Число пропускаемых записей
+}
+
+// SnapshotCursor задает страницу списка, выбираемую по неизменяемому ключу id в пределах снимка.
+// В отличие от Page, страницы не сдвигаются при изменении списка между запросами: подписки, созданные
+// после снимка, не попадают в выборку, а удаленные после снимка продолжают в нее входить. Цена —
+// список отражает состояние на момент первой страницы, и страницу нельзя получить по номеру, только по порядку
+type SnapshotCursor struct {
+	AfterID    uint      // ID последней записи предыдущей страницы (0 — первая страница)
+	MaxID      uint      // Наибольший ID подписки на момент снимка (0 — снимок фиксируется при запросе)
+	SnapshotAt time.Time // Момент снимка
+}
+
+// SnapshotPage — страница списка в пределах снимка
+type SnapshotPage struct {
+	UserIDs []uint
+	Next    SnapshotCursor // Курсор следующей страницы
+	Done    bool           // Страница последняя
+}
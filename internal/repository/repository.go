@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/watchlist-kata/protos/media"
 	"github.com/watchlist-kata/protos/review"
@@ -15,17 +20,53 @@ import (
 	"github.com/watchlist-kata/protos/user"
 	"github.com/watchlist-kata/protos/watchlist"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SubscriptionRepository представляет интерфейс репозитория для работы с подписками
 type SubscriptionRepository interface {
-	Subscribe(ctx context.Context, subscriberID uint, userID uint) error
+	Subscribe(ctx context.Context, subscriberID uint, userID uint) (CreatedSubscription, error)
 	Unsubscribe(ctx context.Context, subscriberID uint, userID uint) error
+	UnsubscribeBatch(ctx context.Context, subscriberID uint, userIDs []uint) ([]uint, error)
+	MergeUser(ctx context.Context, fromID uint, toID uint) (MergeResult, error)
+	GetFollowersForMigration(ctx context.Context, oldUserID uint, batchSize int, handle func([]uint) error) error
+	BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint, batchSize int) ([]uint, error)
 	GetSubscriptions(ctx context.Context, userID uint) ([]uint, error)
 	GetSubscribers(ctx context.Context, userID uint) ([]uint, error)
+	GetSubscribersWithUsernames(ctx context.Context, userID uint, page Page) ([]UserSummary, error)
+	GetSubscribersSnapshot(ctx context.Context, userID uint, cursor SnapshotCursor, limit int) (SnapshotPage, error)
+	GetMutualSubscribers(ctx context.Context, userID uint, page Page) (MutualSubscribers, error)
+	ResolveUsernames(ctx context.Context, userIDs []uint) ([]UserSummary, error)
+	CountSubscribersSince(ctx context.Context, userID uint, since time.Time) (int64, error)
+	CountSubscriptions(ctx context.Context, userID uint) (int64, error)
+	GetProfileConnections(ctx context.Context, userID uint, page Page) (ProfileConnections, error)
+	GetSubscriptionGrowth(ctx context.Context, userID uint, from time.Time, to time.Time, bucket GrowthBucket) ([]GrowthPoint, error)
+	GetNonReciprocalFollowers(ctx context.Context, userID uint, page Page) ([]uint, error)
+	GetNonFollowingBack(ctx context.Context, userID uint, page Page) ([]uint, error)
+	GetSubscriptionsWithMutualFlag(ctx context.Context, userID uint, page Page) ([]SubscriptionWithMutual, error)
+	CountCommonSubscriptions(ctx context.Context, firstUserID uint, secondUserID uint) (int64, error)
+	GetFollowedByUsers(ctx context.Context, followerIDs []uint, page Page) ([]FollowedUser, error)
 	IsSubscribed(ctx context.Context, subscriberID uint, userID uint) (bool, error)
-	GetWatchlistsBySubscription(ctx context.Context, userID uint) ([]*subscription.WatchlistItem, error)
-	GetReviewsBySubscription(ctx context.Context, userID uint) ([]*subscription.ReviewItem, error)
+	GetSubscriptionDetail(ctx context.Context, subscriberID uint, userID uint) (SubscriptionDetail, error)
+	UserExists(ctx context.Context, userID uint) (bool, error)
+	FindMissingUsers(ctx context.Context, userIDs []uint) ([]uint, error)
+	GetSubscriptionStatus(ctx context.Context, subscriberID uint, userID uint) (SubscriptionStatus, error)
+	GetProfileSummary(ctx context.Context, viewerID uint, profileID uint) (ProfileSummary, error)
+	AreConnected(ctx context.Context, firstUserID uint, secondUserID uint) (bool, error)
+	GetRelationships(ctx context.Context, viewerID uint, targetIDs []uint) (map[uint]Relationship, error)
+	GetGroupRelationships(ctx context.Context, userIDs []uint) ([]GroupEdge, error)
+	StreamAllSubscriptions(ctx context.Context, batchSize int, handle func([]SubscriptionRecord) error) error
+	GetSubscriptionHistory(ctx context.Context, subscriberID uint, userID uint) ([]SubscriptionHistoryEntry, error)
+	PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error)
+	FindDuplicateSubscriptions(ctx context.Context) ([]DuplicateSubscription, error)
+	DedupeSubscriptions(ctx context.Context) (int64, error)
+	MarkFeedSeen(ctx context.Context, userID uint, seenAt time.Time) error
+	GetFeedWatermark(ctx context.Context, userID uint) (time.Time, error)
+	GetWatchlistsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*WatchlistFeed, error)
+	GetReviewsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*ReviewFeed, error)
+	GetFeedsForUsers(ctx context.Context, userIDs []uint, opts FeedOptions) (map[uint]*ReviewFeed, error)
+	GetActiveSubscriptions(ctx context.Context, userID uint, limit int, opts FeedOptions) (*ActiveSubscriptions, error)
+	GetInactiveSubscriptions(ctx context.Context, userID uint, inactiveSince time.Time, opts FeedOptions) (*InactiveSubscriptions, error)
 }
 
 // PostgresSubscriptionRepository реализует SubscriptionRepository для PostgreSQL
@@ -36,62 +77,111 @@ type PostgresSubscriptionRepository struct {
 	reviewClient    review.ReviewServiceClient
 	watchlistClient watchlist.WatchlistServiceClient
 	userClient      user.UserServiceClient
+	usernames       UsernameResolver
+	mediaHealth     *dependencyHealth
+	reviewHealth    *dependencyHealth
+	watchlistHealth *dependencyHealth
+	userHealth      *dependencyHealth
+	options         Options
 }
 
 // NewPostgresSubscriptionRepository создает новый экземпляр PostgresSubscriptionRepository
-func NewPostgresSubscriptionRepository(db *gorm.DB, logger *slog.Logger, mediaAddr string, reviewAddr string, watchlistAddr string, userAddr string) (*PostgresSubscriptionRepository, error) {
-	mediaConn, err := grpc.NewClient(
-		mediaAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+func NewPostgresSubscriptionRepository(db *gorm.DB, logger *slog.Logger, mediaCfg, reviewCfg, watchlistCfg, userCfg DownstreamConfig, options Options) (*PostgresSubscriptionRepository, error) {
+	mediaConn, err := dialDownstream(mediaCfg)
 	if err != nil {
 		logger.Error("failed to connect to media service", slog.Any("error", err))
 		return nil, err
 	}
 
-	reviewConn, err := grpc.NewClient(
-		reviewAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	reviewConn, err := dialDownstream(reviewCfg)
 	if err != nil {
 		logger.Error("failed to connect to review service", slog.Any("error", err))
 		return nil, err
 	}
 
-	watchlistConn, err := grpc.NewClient(
-		watchlistAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	watchlistConn, err := dialDownstream(watchlistCfg)
 	if err != nil {
 		logger.Error("failed to connect to watchlist service", slog.Any("error", err))
 		return nil, err
 	}
 
-	userConn, err := grpc.NewClient(
-		userAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	userConn, err := dialDownstream(userCfg)
 	if err != nil {
 		logger.Error("failed to connect to user service", slog.Any("error", err))
 		return nil, err
 	}
 
-	return &PostgresSubscriptionRepository{
+	if options.StartupCheckTimeout > 0 {
+		checkDownstreams(logger, options.StartupCheckTimeout,
+			namedConn{name: "media", conn: mediaConn},
+			namedConn{name: "review", conn: reviewConn},
+			namedConn{name: "watchlist", conn: watchlistConn},
+			namedConn{name: "user", conn: userConn},
+		)
+	}
+
+	return NewPostgresSubscriptionRepositoryFromConns(db, logger, mediaConn, reviewConn, watchlistConn, userConn, options), nil
+}
+
+// NewPostgresSubscriptionRepositoryFromConns создает новый экземпляр PostgresSubscriptionRepository
+// поверх уже установленных подключений к внешним сервисам (например, in-process подключений в тестах)
+func NewPostgresSubscriptionRepositoryFromConns(db *gorm.DB, logger *slog.Logger, mediaConn, reviewConn, watchlistConn, userConn grpc.ClientConnInterface, options Options) *PostgresSubscriptionRepository {
+	r := &PostgresSubscriptionRepository{
 		db:              db,
 		logger:          logger,
 		mediaClient:     media.NewMediaServiceClient(mediaConn),
 		reviewClient:    review.NewReviewServiceClient(reviewConn),
 		watchlistClient: watchlist.NewWatchlistServiceClient(watchlistConn),
 		userClient:      user.NewUserServiceClient(userConn),
-	}, nil
+		mediaHealth:     newDependencyHealth("media"),
+		reviewHealth:    newDependencyHealth("review"),
+		watchlistHealth: newDependencyHealth("watchlist"),
+		userHealth:      newDependencyHealth("user"),
+		options:         options,
+	}
+
+	var usernames UsernameResolver = newDirectUsernameResolver(r.userClient, r.userHealth, logger)
+	if options.UsernameCacheTTL > 0 {
+		usernames = NewCachedUsernameResolver(usernames, options.UsernameCacheTTL)
+	}
+	r.usernames = usernames
+
+	return r
+}
+
+// DependencyHealth возвращает состояние внешних сервисов по их именам
+func (r *PostgresSubscriptionRepository) DependencyHealth() map[string]bool {
+	return map[string]bool{
+		r.mediaHealth.name:     r.mediaHealth.Healthy(),
+		r.reviewHealth.name:    r.reviewHealth.Healthy(),
+		r.watchlistHealth.name: r.watchlistHealth.Healthy(),
+		r.userHealth.name:      r.userHealth.Healthy(),
+	}
+}
+
+// requireDependencies проверяет, что все необходимые внешние сервисы считаются доступными
+func (r *PostgresSubscriptionRepository) requireDependencies(ctx context.Context, dependencies ...*dependencyHealth) error {
+	for _, dependency := range dependencies {
+		if err := dependency.Allow(); err != nil {
+			r.logger.WarnContext(ctx, "skipping call to unavailable service", slog.Any("error", err))
+			return err
+		}
+	}
+	return nil
+}
+
+// CreatedSubscription описывает созданную подписку
+type CreatedSubscription struct {
+	ID        uint
+	CreatedAt time.Time
 }
 
 // Subscribe добавляет подписку на пользователя
-func (r *PostgresSubscriptionRepository) Subscribe(ctx context.Context, subscriberID uint, userID uint) error {
+func (r *PostgresSubscriptionRepository) Subscribe(ctx context.Context, subscriberID uint, userID uint) (CreatedSubscription, error) {
 	select {
 	case <-ctx.Done():
 		r.logger.ErrorContext(ctx, "Subscribe operation canceled", slog.Any("error", ctx.Err()))
-		return ctx.Err()
+		return CreatedSubscription{}, ctx.Err()
 	default:
 	}
 
@@ -103,11 +193,11 @@ func (r *PostgresSubscriptionRepository) Subscribe(ctx context.Context, subscrib
 
 	if err := r.db.Create(subscription).Error; err != nil {
 		r.logger.ErrorContext(ctx, "failed to create subscription", slog.Any("error", err))
-		return err
+		return CreatedSubscription{}, err
 	}
 
-	r.logger.InfoContext(ctx, "subscription created successfully")
-	return nil
+	r.logger.InfoContext(ctx, "subscription created successfully", slog.Uint64("subscription_id", uint64(subscription.ID)))
+	return CreatedSubscription{ID: subscription.ID, CreatedAt: subscription.CreatedAt}, nil
 }
 
 // Unsubscribe удаляет подписку пользователя
@@ -128,7 +218,182 @@ func (r *PostgresSubscriptionRepository) Unsubscribe(ctx context.Context, subscr
 	return nil
 }
 
-// GetSubscriptions получает список подписок пользователя
+// UnsubscribeBatch удаляет подписки пользователя на несколько пользователей одним запросом
+// и возвращает идентификаторы пользователей, подписки на которых были удалены
+func (r *PostgresSubscriptionRepository) UnsubscribeBatch(ctx context.Context, subscriberID uint, userIDs []uint) ([]uint, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "UnsubscribeBatch operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var deleted []GormSubscription
+	if err := r.db.Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}}}).
+		Where("subscriber_id = ? AND user_id IN ?", subscriberID, userIDs).
+		Delete(&deleted).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to delete subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+
+	removedIDs := make([]uint, 0, len(deleted))
+	for _, subscription := range deleted {
+		removedIDs = append(removedIDs, subscription.UserID)
+	}
+
+	r.logger.InfoContext(ctx, "subscriptions deleted successfully", slog.Int("count", len(removedIDs)))
+	return removedIDs, nil
+}
+
+// MergeResult описывает результат переноса подписок при объединении аккаунтов
+type MergeResult struct {
+	Updated int64 // Число подписок, перенесенных на сохраняемый аккаунт
+	Removed int64 // Число подписок, удаленных как дубликаты или подписки на самого себя
+}
+
+// MergeUser переносит подписки и подписчиков аккаунта fromID на аккаунт toID в одной транзакции.
+// Подписки, которые после переноса совпали бы с уже существующими у toID или стали бы подпиской на самого себя, удаляются
+func (r *PostgresSubscriptionRepository) MergeUser(ctx context.Context, fromID uint, toID uint) (MergeResult, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "MergeUser operation canceled", slog.Any("error", ctx.Err()))
+		return MergeResult{}, ctx.Err()
+	default:
+	}
+
+	var result MergeResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Подписки между объединяемыми аккаунтами стали бы подписками на самого себя
+		selfFollows := tx.Where("subscriber_id IN ? AND user_id IN ? AND (subscriber_id = ? OR user_id = ?)",
+			[]uint{fromID, toID}, []uint{fromID, toID}, fromID, fromID).
+			Delete(&GormSubscription{})
+		if selfFollows.Error != nil {
+			return selfFollows.Error
+		}
+		result.Removed += selfFollows.RowsAffected
+
+		// Подписки fromID на пользователей, на которых уже подписан toID
+		duplicateSubscriptions := tx.Where("subscriber_id = ? AND user_id IN (?)",
+			fromID, tx.Model(&GormSubscription{}).Select("user_id").Where("subscriber_id = ?", toID)).
+			Delete(&GormSubscription{})
+		if duplicateSubscriptions.Error != nil {
+			return duplicateSubscriptions.Error
+		}
+		result.Removed += duplicateSubscriptions.RowsAffected
+
+		// Подписчики fromID, которые уже подписаны на toID
+		duplicateSubscribers := tx.Where("user_id = ? AND subscriber_id IN (?)",
+			fromID, tx.Model(&GormSubscription{}).Select("subscriber_id").Where("user_id = ?", toID)).
+			Delete(&GormSubscription{})
+		if duplicateSubscribers.Error != nil {
+			return duplicateSubscribers.Error
+		}
+		result.Removed += duplicateSubscribers.RowsAffected
+
+		movedSubscriptions := tx.Model(&GormSubscription{}).Where("subscriber_id = ?", fromID).Update("subscriber_id", toID)
+		if movedSubscriptions.Error != nil {
+			return movedSubscriptions.Error
+		}
+		result.Updated += movedSubscriptions.RowsAffected
+
+		movedSubscribers := tx.Model(&GormSubscription{}).Where("user_id = ?", fromID).Update("user_id", toID)
+		if movedSubscribers.Error != nil {
+			return movedSubscribers.Error
+		}
+		result.Updated += movedSubscribers.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to merge user subscriptions", slog.Any("error", err))
+		return MergeResult{}, err
+	}
+
+	r.logger.InfoContext(ctx, "user subscriptions merged successfully",
+		slog.Int64("updated", result.Updated), slog.Int64("removed", result.Removed))
+	return result, nil
+}
+
+// GetFollowersForMigration выгружает подписчиков пользователя пачками по возрастанию их идентификаторов,
+// передавая каждую пачку в handle. Используется при переходе автора на новый аккаунт.
+// Выгрузка прерывается при отмене контекста или ошибке handle
+func (r *PostgresSubscriptionRepository) GetFollowersForMigration(ctx context.Context, oldUserID uint, batchSize int, handle func([]uint) error) error {
+	var lastID uint
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.ErrorContext(ctx, "GetFollowersForMigration operation canceled", slog.Any("error", ctx.Err()))
+			return ctx.Err()
+		default:
+		}
+
+		var followerIDs []uint
+		if err := r.db.Model(&GormSubscription{}).
+			Where("user_id = ? AND subscriber_id > ?", oldUserID, lastID).
+			Order("subscriber_id").
+			Limit(batchSize).
+			Pluck("subscriber_id", &followerIDs).Error; err != nil {
+			r.logger.ErrorContext(ctx, "failed to get followers batch", slog.Any("error", err))
+			return err
+		}
+		if len(followerIDs) == 0 {
+			break
+		}
+		if err := handle(followerIDs); err != nil {
+			return err
+		}
+
+		lastID = followerIDs[len(followerIDs)-1]
+		if len(followerIDs) < batchSize {
+			break
+		}
+	}
+
+	r.logger.InfoContext(ctx, "followers for migration streamed successfully")
+	return nil
+}
+
+// BulkSubscribeFollowersTo подписывает на аккаунт newUserID тех пользователей из followerIDs, которые подписаны на oldUserID.
+// Подписки создаются пачками по batchSize, каждая пачка — отдельным запросом. Уже существующие подписки
+// и подписка нового аккаунта на самого себя пропускаются, поэтому повторный вызов после сбоя безопасен.
+// Возвращает идентификаторы пользователей, для которых подписка была создана
+func (r *PostgresSubscriptionRepository) BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint, batchSize int) ([]uint, error) {
+	var subscribedIDs []uint
+	for start := 0; start < len(followerIDs); start += batchSize {
+		select {
+		case <-ctx.Done():
+			r.logger.ErrorContext(ctx, "BulkSubscribeFollowersTo operation canceled", slog.Any("error", ctx.Err()))
+			return subscribedIDs, ctx.Err()
+		default:
+		}
+
+		end := min(start+batchSize, len(followerIDs))
+		var created []uint
+		if err := r.db.Raw(
+			`INSERT INTO subscription (subscriber_id, user_id, created_at, updated_at)
+			SELECT s.subscriber_id, ?, NOW(), NOW()
+			FROM subscription s
+			WHERE s.user_id = ? AND s.subscriber_id IN ? AND s.subscriber_id <> ? AND s.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM subscription existing
+					WHERE existing.subscriber_id = s.subscriber_id AND existing.user_id = ? AND existing.deleted_at IS NULL
+				)
+			ON CONFLICT DO NOTHING
+			RETURNING subscriber_id`,
+			newUserID, oldUserID, followerIDs[start:end], newUserID, newUserID,
+		).Scan(&created).Error; err != nil {
+			r.logger.ErrorContext(ctx, "failed to create migrated subscriptions", slog.Any("error", err))
+			return subscribedIDs, err
+		}
+		subscribedIDs = append(subscribedIDs, created...)
+	}
+
+	r.logger.InfoContext(ctx, "followers subscribed to new account", slog.Int("count", len(subscribedIDs)))
+	return subscribedIDs, nil
+}
+
+// GetSubscriptions получает список подписок пользователя, упорядоченный по ID пользователя.
+// Порядок подписок определяет порядок обхода при построении лент, поэтому он должен быть детерминированным
 func (r *PostgresSubscriptionRepository) GetSubscriptions(ctx context.Context, userID uint) ([]uint, error) {
 	select {
 	case <-ctx.Done():
@@ -138,7 +403,7 @@ func (r *PostgresSubscriptionRepository) GetSubscriptions(ctx context.Context, u
 	}
 
 	var subscriptions []GormSubscription
-	if err := r.db.Where("subscriber_id = ?", userID).Find(&subscriptions).Error; err != nil {
+	if err := r.db.Where("subscriber_id = ?", userID).Order("user_id").Find(&subscriptions).Error; err != nil {
 		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
 		return nil, err
 	}
@@ -176,146 +441,1532 @@ func (r *PostgresSubscriptionRepository) GetSubscribers(ctx context.Context, use
 	return subscriberIDs, nil
 }
 
-// IsSubscribed проверяет, подписан ли пользователь на другого пользователя
-func (r *PostgresSubscriptionRepository) IsSubscribed(ctx context.Context, subscriberID uint, userID uint) (bool, error) {
+// CountSubscribersSince считает подписчиков пользователя, подписавшихся позже указанного момента
+func (r *PostgresSubscriptionRepository) CountSubscribersSince(ctx context.Context, userID uint, since time.Time) (int64, error) {
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, "IsSubscribed operation canceled", slog.Any("error", ctx.Err()))
-		return false, ctx.Err()
+		r.logger.ErrorContext(ctx, "CountSubscribersSince operation canceled", slog.Any("error", ctx.Err()))
+		return 0, ctx.Err()
 	default:
 	}
 
-	var subscription GormSubscription
-	if err := r.db.Where("subscriber_id = ? AND user_id = ?", subscriberID, userID).First(&subscription).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.logger.WarnContext(ctx, "subscription not found")
-			return false, nil
-		}
-		r.logger.ErrorContext(ctx, "failed to check subscription", slog.Any("error", err))
-		return false, err
+	var count int64
+	if err := r.db.Model(&GormSubscription{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to count subscribers", slog.Any("error", err))
+		return 0, err
 	}
 
-	r.logger.InfoContext(ctx, "subscription checked successfully")
-	return true, nil
+	r.logger.InfoContext(ctx, "subscribers counted successfully")
+	return count, nil
 }
 
-// WatchlistItem представляет элемент вотчлиста
-type WatchlistItem struct {
-	MediaID uint   `json:"media_id"`
-	UserID  uint   `json:"user_id"`
-	Title   string `json:"title"`
-	Desc    string `json:"description"`
+// CountSubscriptions считает пользователей, на которых подписан пользователь
+func (r *PostgresSubscriptionRepository) CountSubscriptions(ctx context.Context, userID uint) (int64, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "CountSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return 0, ctx.Err()
+	default:
+	}
+
+	var count int64
+	if err := r.db.Model(&GormSubscription{}).
+		Where("subscriber_id = ?", userID).
+		Count(&count).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to count subscriptions", slog.Any("error", err))
+		return 0, err
+	}
+
+	r.logger.InfoContext(ctx, "subscriptions counted successfully")
+	return count, nil
 }
 
-// ReviewItem представляет элемент отзыва
-type ReviewItem struct {
-	ReviewID uint   `json:"review_id"`
-	UserID   uint   `json:"user_id"`
-	Content  string `json:"content"`
-	Rating   int    `json:"rating"`
+// ConnectionPage представляет страницу подписок или подписчиков пользователя
+type ConnectionPage struct {
+	UserIDs []uint
+	Total   int64 // Общее число подписок или подписчиков
+}
+
+// ProfileConnections содержит страницы подписок и подписчиков пользователя для профиля
+type ProfileConnections struct {
+	Subscriptions ConnectionPage
+	Subscribers   ConnectionPage
 }
 
-// GetWatchlistsBySubscription получает вотчлисты пользователей, на которых подписан пользователь
-func (r *PostgresSubscriptionRepository) GetWatchlistsBySubscription(ctx context.Context, userID uint) ([]*subscription.WatchlistItem, error) {
+// GetProfileConnections получает страницу подписок и страницу подписчиков пользователя вместе с их общим числом.
+// Четыре запроса выполняются параллельно
+func (r *PostgresSubscriptionRepository) GetProfileConnections(ctx context.Context, userID uint, page Page) (ProfileConnections, error) {
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, "GetWatchlistsBySubscription operation canceled", slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "GetProfileConnections operation canceled", slog.Any("error", ctx.Err()))
+		return ProfileConnections{}, ctx.Err()
+	default:
+	}
+
+	connections := ProfileConnections{
+		Subscriptions: ConnectionPage{UserIDs: make([]uint, 0)},
+		Subscribers:   ConnectionPage{UserIDs: make([]uint, 0)},
+	}
+	var group errgroup.Group
+	group.Go(func() error {
+		return r.db.Model(&GormSubscription{}).
+			Where("subscriber_id = ?", userID).
+			Order("user_id").
+			Limit(page.Limit).
+			Offset(page.Offset).
+			Pluck("user_id", &connections.Subscriptions.UserIDs).Error
+	})
+	group.Go(func() error {
+		return r.db.Model(&GormSubscription{}).
+			Where("subscriber_id = ?", userID).
+			Count(&connections.Subscriptions.Total).Error
+	})
+	group.Go(func() error {
+		return r.db.Model(&GormSubscription{}).
+			Where("user_id = ?", userID).
+			Order("subscriber_id").
+			Limit(page.Limit).
+			Offset(page.Offset).
+			Pluck("subscriber_id", &connections.Subscribers.UserIDs).Error
+	})
+	group.Go(func() error {
+		return r.db.Model(&GormSubscription{}).
+			Where("user_id = ?", userID).
+			Count(&connections.Subscribers.Total).Error
+	})
+	if err := group.Wait(); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get profile connections", slog.Any("error", err))
+		return ProfileConnections{}, err
+	}
+
+	r.logger.InfoContext(ctx, "profile connections fetched successfully",
+		slog.Int64("subscriptions_total", connections.Subscriptions.Total), slog.Int64("subscribers_total", connections.Subscribers.Total))
+	return connections, nil
+}
+
+// UserSummary содержит ID пользователя и его имя
+type UserSummary struct {
+	UserID   uint
+	Username string
+	Deleted  bool // Пользователь удален в сервисе пользователей, имя недоступно
+}
+
+// GetSubscribersWithUsernames получает страницу подписчиков пользователя вместе с их именами.
+// Подписчики, удаленные в сервисе пользователей, возвращаются с флагом Deleted, чтобы не сдвигать страницы
+func (r *PostgresSubscriptionRepository) GetSubscribersWithUsernames(ctx context.Context, userID uint, page Page) ([]UserSummary, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscribersWithUsernames operation canceled", slog.Any("error", ctx.Err()))
 		return nil, ctx.Err()
 	default:
 	}
 
-	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
+	var subscriberIDs []uint
+	if err := r.db.Model(&GormSubscription{}).
+		Where("user_id = ?", userID).
+		Order("subscriber_id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Pluck("subscriber_id", &subscriberIDs).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscribers", slog.Any("error", err))
+		return nil, err
+	}
+
+	subscribers, err := r.ResolveUsernames(ctx, subscriberIDs)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
 		return nil, err
 	}
 
-	var watchlists []*subscription.WatchlistItem
-	for _, subscribedToID := range subscribedToIDs {
-		watchlistResponse, err := r.watchlistClient.GetWatchlist(ctx, &watchlist.GetWatchlistRequest{UserId: int64(subscribedToID)})
+	r.logger.InfoContext(ctx, "subscribers with usernames fetched successfully")
+	return subscribers, nil
+}
+
+// GetSubscribersSnapshot получает страницу подписчиков пользователя в порядке создания подписок по снимку,
+// зафиксированному при запросе первой страницы (cursor.MaxID == 0). Мягко удаленные подписки учитываются
+// по моменту удаления; подписки, окончательно удаленные задачей очистки, из снимка пропадают
+func (r *PostgresSubscriptionRepository) GetSubscribersSnapshot(ctx context.Context, userID uint, cursor SnapshotCursor, limit int) (SnapshotPage, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscribersSnapshot operation canceled", slog.Any("error", ctx.Err()))
+		return SnapshotPage{}, ctx.Err()
+	default:
+	}
+
+	if cursor.MaxID == 0 {
+		cursor.SnapshotAt = time.Now()
+		if err := r.db.Unscoped().Model(&GormSubscription{}).
+			Select("COALESCE(MAX(id), 0)").
+			Scan(&cursor.MaxID).Error; err != nil {
+			r.logger.ErrorContext(ctx, "failed to capture subscribers snapshot", slog.Any("error", err))
+			return SnapshotPage{}, err
+		}
+	}
+
+	var subscriptions []GormSubscription
+	if err := r.db.Unscoped().
+		Select("id", "subscriber_id").
+		Where("user_id = ? AND id > ? AND id <= ? AND created_at <= ?", userID, cursor.AfterID, cursor.MaxID, cursor.SnapshotAt).
+		Where("(deleted_at IS NULL OR deleted_at > ?)", cursor.SnapshotAt).
+		Order("id").
+		Limit(limit).
+		Find(&subscriptions).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscribers snapshot page", slog.Any("error", err))
+		return SnapshotPage{}, err
+	}
+
+	page := SnapshotPage{
+		UserIDs: make([]uint, 0, len(subscriptions)),
+		Next:    cursor,
+		Done:    len(subscriptions) < limit,
+	}
+	for _, subscription := range subscriptions {
+		page.UserIDs = append(page.UserIDs, subscription.SubscriberID)
+	}
+	if len(subscriptions) > 0 {
+		page.Next.AfterID = subscriptions[len(subscriptions)-1].ID
+	}
+
+	r.logger.InfoContext(ctx, "subscribers snapshot page fetched successfully", slog.Int("count", len(page.UserIDs)))
+	return page, nil
+}
+
+// ResolveUsernames получает имена указанных пользователей в том же порядке.
+// Пользователи, удаленные в сервисе пользователей, возвращаются с флагом Deleted
+func (r *PostgresSubscriptionRepository) ResolveUsernames(ctx context.Context, userIDs []uint) ([]UserSummary, error) {
+	users := make([]UserSummary, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	if err := r.requireDependencies(ctx, r.userHealth); err != nil {
+		return nil, err
+	}
+
+	// Сервис пользователей не поддерживает пакетное получение, поэтому имена разрешаются по одному
+	for _, userID := range userIDs {
+		username, err := r.usernames.ResolveUsername(ctx, userID)
+		if status.Code(err) == codes.NotFound {
+			users = append(users, UserSummary{UserID: userID, Deleted: true})
+			continue
+		}
 		if err != nil {
-			r.logger.ErrorContext(ctx, "failed to get watchlist from watchlist service", slog.Any("error", err))
 			return nil, err
 		}
+		users = append(users, UserSummary{UserID: userID, Username: username})
+	}
 
-		for _, watchlistItem := range watchlistResponse.Watchlists {
-			mediaResponse, err := r.mediaClient.GetMediaByID(ctx, &media.GetMediaByIDRequest{Id: watchlistItem.MediaId})
-			if err != nil {
-				r.logger.ErrorContext(ctx, "failed to get media info from media service", slog.Any("error", err))
-				return nil, err
-			}
+	return users, nil
+}
 
-			userResponse, err := r.userClient.GetByID(ctx, &user.GetUserRequest{Id: int64(subscribedToID)})
-			if err != nil {
-				r.logger.ErrorContext(ctx, "failed to get user info from user service", slog.Any("error", err))
-				return nil, err
-			}
+// MutualSubscribers представляет страницу взаимных подписчиков пользователя
+type MutualSubscribers struct {
+	UserIDs []uint
+	Total   int64         // Общее число взаимных подписчиков
+	Users   []UserSummary // Имена пользователей страницы (заполняются, только если запрошены)
+}
 
-			watchlistItemInfo := &subscription.WatchlistItem{
-				MediaId:     watchlistItem.MediaId,
-				UserId:      watchlistItem.UserId,
-				UserName:    userResponse.User.Username,
-				Title:       mediaResponse.NameEn,
-				Description: mediaResponse.Description,
-			}
-			watchlists = append(watchlists, watchlistItemInfo)
+// GetMutualSubscribers получает страницу подписчиков пользователя, на которых он подписан в ответ,
+// и общее число таких подписчиков одним запросом. Учитываются не более GraphMaxFanout подписок пользователя
+func (r *PostgresSubscriptionRepository) GetMutualSubscribers(ctx context.Context, userID uint, page Page) (MutualSubscribers, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetMutualSubscribers operation canceled", slog.Any("error", ctx.Err()))
+		return MutualSubscribers{}, ctx.Err()
+	default:
+	}
+
+	// Общее число возвращается и для страницы за пределами списка: тогда user_id в единственной строке равен NULL
+	var rows []struct {
+		UserID *uint
+		Total  int64
+	}
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`WITH following AS (
+				SELECT user_id FROM subscription
+				WHERE subscriber_id = ? AND deleted_at IS NULL
+				ORDER BY user_id
+				LIMIT ?
+			), mutual AS (
+				SELECT following.user_id
+				FROM following
+				JOIN subscription followers
+					ON followers.subscriber_id = following.user_id
+					AND followers.user_id = ?
+					AND followers.deleted_at IS NULL
+			)
+			SELECT page.user_id, counted.total
+			FROM (SELECT COUNT(*) AS total FROM mutual) counted
+			LEFT JOIN LATERAL (
+				SELECT user_id FROM mutual ORDER BY user_id LIMIT ? OFFSET ?
+			) page ON true`,
+			userID, r.graphFanoutLimit(), userID, page.Limit, page.Offset,
+		).Scan(&rows).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get mutual subscribers", slog.Any("error", err))
+		return MutualSubscribers{}, err
+	}
+
+	mutual := MutualSubscribers{UserIDs: make([]uint, 0, len(rows))}
+	for _, row := range rows {
+		mutual.Total = row.Total
+		if row.UserID != nil {
+			mutual.UserIDs = append(mutual.UserIDs, *row.UserID)
 		}
 	}
 
-	r.logger.InfoContext(ctx, "watchlists fetched successfully")
-	return watchlists, nil
+	r.logger.InfoContext(ctx, "mutual subscribers fetched successfully")
+	return mutual, nil
 }
 
-// GetReviewsBySubscription получает отзывы пользователей, на которых подписан пользователь
-func (r *PostgresSubscriptionRepository) GetReviewsBySubscription(ctx context.Context, userID uint) ([]*subscription.ReviewItem, error) {
+// GetNonReciprocalFollowers получает подписчиков пользователя, на которых он сам не подписан
+func (r *PostgresSubscriptionRepository) GetNonReciprocalFollowers(ctx context.Context, userID uint, page Page) ([]uint, error) {
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, "GetReviewsBySubscription operation canceled", slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "GetNonReciprocalFollowers operation canceled", slog.Any("error", ctx.Err()))
 		return nil, ctx.Err()
 	default:
 	}
 
-	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
-	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
+	var followerIDs []uint
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`SELECT subscriber_id FROM subscription WHERE user_id = ? AND deleted_at IS NULL
+			EXCEPT
+			SELECT user_id FROM subscription WHERE subscriber_id = ? AND deleted_at IS NULL
+			ORDER BY subscriber_id
+			LIMIT ? OFFSET ?`,
+			userID, userID, page.Limit, page.Offset,
+		).Scan(&followerIDs).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get non-reciprocal followers", slog.Any("error", err))
 		return nil, err
 	}
 
-	var reviews []*subscription.ReviewItem
-	for _, subscribedToID := range subscribedToIDs {
-		reviewResponse, err := r.reviewClient.GetByUser(ctx, &review.GetByUserRequest{UserId: int64(subscribedToID)})
-		if err != nil {
-			r.logger.ErrorContext(ctx, "failed to get reviews from review service", slog.Any("error", err))
-			return nil, err
-		}
+	r.logger.InfoContext(ctx, "non-reciprocal followers fetched successfully")
+	return followerIDs, nil
+}
 
-		for _, reviewProto := range reviewResponse.Reviews {
+// FollowedUser — пользователь и число подписчиков из заданного набора
+type FollowedUser struct {
+	UserID        uint
+	FollowerCount int64 // Число пользователей из набора, подписанных на пользователя
+}
 
-			mediaResponse, err := r.mediaClient.GetMediaByID(ctx, &media.GetMediaByIDRequest{Id: reviewProto.MediaId})
-			if err != nil {
-				r.logger.ErrorContext(ctx, "failed to get media info from media service", slog.Any("error", err))
-				return nil, err
-			}
+// GetFollowedByUsers получает пользователей, на которых подписан хотя бы один из followerIDs, с числом таких подписчиков.
+// Пользователи упорядочены по убыванию числа подписчиков из набора, затем по ID.
+// Из набора учитываются не более GraphMaxFanout пользователей с наименьшими ID
+func (r *PostgresSubscriptionRepository) GetFollowedByUsers(ctx context.Context, followerIDs []uint, page Page) ([]FollowedUser, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetFollowedByUsers operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
 
-			userResponse, err := r.userClient.GetByID(ctx, &user.GetUserRequest{Id: int64(subscribedToID)})
-			if err != nil {
-				r.logger.ErrorContext(ctx, "failed to get user info from user service", slog.Any("error", err))
-				return nil, err
-			}
+	if len(followerIDs) == 0 {
+		return []FollowedUser{}, nil
+	}
 
-			reviewItem := &subscription.ReviewItem{
-				ReviewId:  reviewProto.Id,
-				UserId:    reviewProto.UserId,
-				UserName:  userResponse.User.Username,
-				Content:   reviewProto.Content,
-				Rating:    reviewProto.Rating,
-				MediaName: mediaResponse.NameEn,
-				MediaYear: mediaResponse.Year,
-			}
-			reviews = append(reviews, reviewItem)
-		}
+	var followed []FollowedUser
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`SELECT user_id, COUNT(*) AS follower_count
+			FROM subscription
+			WHERE subscriber_id IN ? AND deleted_at IS NULL
+			GROUP BY user_id
+			ORDER BY follower_count DESC, user_id
+			LIMIT ? OFFSET ?`,
+			r.capGraphFanout(followerIDs), page.Limit, page.Offset,
+		).Scan(&followed).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get users followed by set", slog.Any("error", err))
+		return nil, err
 	}
 
-	r.logger.InfoContext(ctx, "reviews fetched successfully")
-	return reviews, nil
+	r.logger.InfoContext(ctx, "users followed by set fetched successfully", slog.Int("count", len(followed)))
+	return followed, nil
+}
+
+// GetNonFollowingBack получает пользователей, на которых подписан пользователь, но которые не подписаны на него
+func (r *PostgresSubscriptionRepository) GetNonFollowingBack(ctx context.Context, userID uint, page Page) ([]uint, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetNonFollowingBack operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var subscribedToIDs []uint
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`SELECT user_id FROM subscription WHERE subscriber_id = ? AND deleted_at IS NULL
+			EXCEPT
+			SELECT subscriber_id FROM subscription WHERE user_id = ? AND deleted_at IS NULL
+			ORDER BY user_id
+			LIMIT ? OFFSET ?`,
+			userID, userID, page.Limit, page.Offset,
+		).Scan(&subscribedToIDs).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions not following back", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "subscriptions not following back fetched successfully")
+	return subscribedToIDs, nil
+}
+
+// SubscriptionWithMutual — пользователь из подписок и признак взаимной подписки
+type SubscriptionWithMutual struct {
+	UserID   uint
+	IsMutual bool // Пользователь подписан в ответ
+}
+
+// GetSubscriptionsWithMutualFlag получает страницу подписок пользователя с признаком взаимной подписки.
+// Признак вычисляется одним запросом через соединение таблицы подписок с обратными парами
+func (r *PostgresSubscriptionRepository) GetSubscriptionsWithMutualFlag(ctx context.Context, userID uint, page Page) ([]SubscriptionWithMutual, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscriptionsWithMutualFlag operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	subscriptions := make([]SubscriptionWithMutual, 0)
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`SELECT s.user_id, r.id IS NOT NULL AS is_mutual
+			FROM subscription s
+			LEFT JOIN subscription r ON r.subscriber_id = s.user_id AND r.user_id = s.subscriber_id AND r.deleted_at IS NULL
+			WHERE s.subscriber_id = ? AND s.deleted_at IS NULL
+			ORDER BY s.user_id
+			LIMIT ? OFFSET ?`,
+			userID, page.Limit, page.Offset,
+		).Scan(&subscriptions).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions with mutual flag", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "subscriptions with mutual flag fetched successfully", slog.Int("count", len(subscriptions)))
+	return subscriptions, nil
+}
+
+// CountCommonSubscriptions считает пользователей, на которых подписаны оба пользователя, одним запросом COUNT
+// без выборки их ID. Учитываются не более GraphMaxFanout подписок первого пользователя
+func (r *PostgresSubscriptionRepository) CountCommonSubscriptions(ctx context.Context, firstUserID uint, secondUserID uint) (int64, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "CountCommonSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return 0, ctx.Err()
+	default:
+	}
+
+	var count int64
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`WITH a AS (
+				SELECT user_id FROM subscription
+				WHERE subscriber_id = ? AND deleted_at IS NULL
+				ORDER BY user_id
+				LIMIT ?
+			)
+			SELECT COUNT(*)
+			FROM a
+			JOIN subscription b ON b.user_id = a.user_id AND b.subscriber_id = ? AND b.deleted_at IS NULL`,
+			firstUserID, r.graphFanoutLimit(), secondUserID,
+		).Scan(&count).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to count common subscriptions", slog.Any("error", err))
+		return 0, err
+	}
+
+	r.logger.InfoContext(ctx, "common subscriptions counted successfully", slog.Int64("count", count))
+	return count, nil
+}
+
+// IsSubscribed проверяет, подписан ли пользователь на другого пользователя
+func (r *PostgresSubscriptionRepository) IsSubscribed(ctx context.Context, subscriberID uint, userID uint) (bool, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "IsSubscribed operation canceled", slog.Any("error", ctx.Err()))
+		return false, ctx.Err()
+	default:
+	}
+
+	// Проверяем только наличие строки, не загружая ее столбцы
+	var found []int
+	result := r.db.Model(&GormSubscription{}).
+		Select("1").
+		Where("subscriber_id = ? AND user_id = ?", subscriberID, userID).
+		Limit(1).
+		Find(&found)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "failed to check subscription", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		r.logger.WarnContext(ctx, "subscription not found")
+		return false, nil
+	}
+
+	r.logger.InfoContext(ctx, "subscription checked successfully")
+	return true, nil
+}
+
+// SubscriptionDetail описывает подписку одного пользователя на другого вместе с датой ее создания
+type SubscriptionDetail struct {
+	IsSubscribed bool      // Пользователь подписан на другого пользователя
+	Since        time.Time // Момент создания подписки (нулевой, если подписки нет)
+}
+
+// GetSubscriptionDetail проверяет подписку и возвращает дату ее создания, загружая только created_at
+func (r *PostgresSubscriptionRepository) GetSubscriptionDetail(ctx context.Context, subscriberID uint, userID uint) (SubscriptionDetail, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscriptionDetail operation canceled", slog.Any("error", ctx.Err()))
+		return SubscriptionDetail{}, ctx.Err()
+	default:
+	}
+
+	var createdAt []time.Time
+	if err := r.db.Model(&GormSubscription{}).
+		Where("subscriber_id = ? AND user_id = ?", subscriberID, userID).
+		Limit(1).
+		Pluck("created_at", &createdAt).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscription detail", slog.Any("error", err))
+		return SubscriptionDetail{}, err
+	}
+	if len(createdAt) == 0 {
+		return SubscriptionDetail{}, nil
+	}
+
+	r.logger.InfoContext(ctx, "subscription detail fetched successfully")
+	return SubscriptionDetail{IsSubscribed: true, Since: createdAt[0]}, nil
+}
+
+// UserExists проверяет через сервис пользователей, существует ли пользователь
+func (r *PostgresSubscriptionRepository) UserExists(ctx context.Context, userID uint) (bool, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "UserExists operation canceled", slog.Any("error", ctx.Err()))
+		return false, ctx.Err()
+	default:
+	}
+
+	if err := r.requireDependencies(ctx, r.userHealth); err != nil {
+		return false, err
+	}
+
+	_, err := r.userClient.GetByID(ctx, &user.GetUserRequest{Id: int64(userID)})
+	r.userHealth.Record(err)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get user info from user service", slog.Any("error", err))
+		return false, &DownstreamError{Service: "user", Method: "GetByID", Err: err}
+	}
+
+	return true, nil
+}
+
+// userExistenceConcurrency ограничивает число одновременных вызовов сервиса пользователей при проверке списка пользователей
+const userExistenceConcurrency = 8
+
+// FindMissingUsers возвращает пользователей из userIDs, которых нет в сервисе пользователей, в порядке первого упоминания.
+// Сервис пользователей не поддерживает пакетную проверку, поэтому каждый уникальный ID проверяется отдельным вызовом,
+// не более userExistenceConcurrency одновременно. При включенном кэше имен найденные ранее пользователи берутся из кэша
+func (r *PostgresSubscriptionRepository) FindMissingUsers(ctx context.Context, userIDs []uint) ([]uint, error) {
+	if err := r.requireDependencies(ctx, r.userHealth); err != nil {
+		return nil, err
+	}
+
+	uniqueIDs := make([]uint, 0, len(userIDs))
+	seen := make(map[uint]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		uniqueIDs = append(uniqueIDs, userID)
+	}
+
+	missing := make([]bool, len(uniqueIDs))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(userExistenceConcurrency)
+	for i, userID := range uniqueIDs {
+		group.Go(func() error {
+			_, err := r.usernames.ResolveUsername(groupCtx, userID)
+			if status.Code(err) == codes.NotFound {
+				missing[i] = true
+				return nil
+			}
+			return err
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	missingIDs := make([]uint, 0)
+	for i, userID := range uniqueIDs {
+		if missing[i] {
+			missingIDs = append(missingIDs, userID)
+		}
+	}
+
+	r.logger.InfoContext(ctx, "users existence checked", slog.Int("checked", len(uniqueIDs)), slog.Int("missing", len(missingIDs)))
+	return missingIDs, nil
+}
+
+// SubscriptionStatus описывает подписку одного пользователя на другого с учетом обратной подписки
+type SubscriptionStatus struct {
+	IsSubscribed bool // Пользователь подписан на другого пользователя
+	IsMutual     bool // Подписка взаимная
+}
+
+// GetSubscriptionStatus проверяет подписку и ее взаимность одним запросом
+func (r *PostgresSubscriptionRepository) GetSubscriptionStatus(ctx context.Context, subscriberID uint, userID uint) (SubscriptionStatus, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscriptionStatus operation canceled", slog.Any("error", ctx.Err()))
+		return SubscriptionStatus{}, ctx.Err()
+	default:
+	}
+
+	var result struct {
+		Forward  bool
+		Backward bool
+	}
+	if err := r.db.Raw(
+		`SELECT
+			EXISTS (SELECT 1 FROM subscription WHERE subscriber_id = ? AND user_id = ? AND deleted_at IS NULL) AS forward,
+			EXISTS (SELECT 1 FROM subscription WHERE subscriber_id = ? AND user_id = ? AND deleted_at IS NULL) AS backward`,
+		subscriberID, userID, userID, subscriberID,
+	).Scan(&result).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to check subscription status", slog.Any("error", err))
+		return SubscriptionStatus{}, err
+	}
+
+	r.logger.InfoContext(ctx, "subscription status checked successfully")
+	return SubscriptionStatus{
+		IsSubscribed: result.Forward,
+		IsMutual:     result.Forward && result.Backward,
+	}, nil
+}
+
+// ProfileSummary описывает связи профиля для его заголовка
+type ProfileSummary struct {
+	SubscriptionCount int64 // Число пользователей, на которых подписан профиль
+	SubscriberCount   int64 // Число подписчиков профиля
+	MutualCount       int64 // Число взаимных подписок профиля
+	ViewerSubscribed  bool  // Просматривающий пользователь подписан на профиль
+}
+
+// GetProfileSummary получает число подписок, подписчиков и взаимных подписок профиля и проверяет
+// подписку просматривающего пользователя одним запросом
+func (r *PostgresSubscriptionRepository) GetProfileSummary(ctx context.Context, viewerID uint, profileID uint) (ProfileSummary, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetProfileSummary operation canceled", slog.Any("error", ctx.Err()))
+		return ProfileSummary{}, ctx.Err()
+	default:
+	}
+
+	var summary ProfileSummary
+	if err := r.withGraphQueryTimeout(func(tx *gorm.DB) error {
+		return tx.Raw(
+			`SELECT
+				(SELECT COUNT(*) FROM subscription WHERE subscriber_id = ? AND deleted_at IS NULL) AS subscription_count,
+				(SELECT COUNT(*) FROM subscription WHERE user_id = ? AND deleted_at IS NULL) AS subscriber_count,
+				(SELECT COUNT(*)
+					FROM subscription followers
+					JOIN subscription following
+						ON following.subscriber_id = followers.user_id
+						AND following.user_id = followers.subscriber_id
+						AND following.deleted_at IS NULL
+					WHERE followers.user_id = ? AND followers.deleted_at IS NULL) AS mutual_count,
+				EXISTS (SELECT 1 FROM subscription WHERE subscriber_id = ? AND user_id = ? AND deleted_at IS NULL) AS viewer_subscribed`,
+			profileID, profileID, profileID, viewerID, profileID,
+		).Scan(&summary).Error
+	}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to get profile summary", slog.Any("error", err))
+		return ProfileSummary{}, err
+	}
+
+	r.logger.InfoContext(ctx, "profile summary fetched successfully")
+	return summary, nil
+}
+
+// AreConnected проверяет одним запросом, подписан ли хотя бы один из пользователей на другого
+func (r *PostgresSubscriptionRepository) AreConnected(ctx context.Context, firstUserID uint, secondUserID uint) (bool, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "AreConnected operation canceled", slog.Any("error", ctx.Err()))
+		return false, ctx.Err()
+	default:
+	}
+
+	var connected bool
+	if err := r.db.Raw(
+		`SELECT EXISTS (
+			SELECT 1 FROM subscription
+			WHERE ((subscriber_id = ? AND user_id = ?) OR (subscriber_id = ? AND user_id = ?))
+				AND deleted_at IS NULL
+		)`,
+		firstUserID, secondUserID, secondUserID, firstUserID,
+	).Scan(&connected).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to check connection", slog.Any("error", err))
+		return false, err
+	}
+
+	r.logger.InfoContext(ctx, "connection checked successfully")
+	return connected, nil
+}
+
+// Relationship описывает связь просматривающего пользователя с другим пользователем
+type Relationship struct {
+	Follows    bool // Просматривающий подписан на пользователя
+	FollowedBy bool // Пользователь подписан на просматривающего
+}
+
+// GetRelationships получает связи пользователя с каждым из указанных пользователей двумя запросами
+func (r *PostgresSubscriptionRepository) GetRelationships(ctx context.Context, viewerID uint, targetIDs []uint) (map[uint]Relationship, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetRelationships operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var followedIDs []uint
+	if err := r.db.Model(&GormSubscription{}).
+		Where("subscriber_id = ? AND user_id IN ?", viewerID, targetIDs).
+		Pluck("user_id", &followedIDs).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get followed users", slog.Any("error", err))
+		return nil, err
+	}
+
+	var followerIDs []uint
+	if err := r.db.Model(&GormSubscription{}).
+		Where("user_id = ? AND subscriber_id IN ?", viewerID, targetIDs).
+		Pluck("subscriber_id", &followerIDs).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get followers", slog.Any("error", err))
+		return nil, err
+	}
+
+	relationships := make(map[uint]Relationship, len(targetIDs))
+	for _, targetID := range targetIDs {
+		relationships[targetID] = Relationship{}
+	}
+	for _, id := range followedIDs {
+		relationship := relationships[id]
+		relationship.Follows = true
+		relationships[id] = relationship
+	}
+	for _, id := range followerIDs {
+		relationship := relationships[id]
+		relationship.FollowedBy = true
+		relationships[id] = relationship
+	}
+
+	r.logger.InfoContext(ctx, "relationships fetched successfully")
+	return relationships, nil
+}
+
+// GroupEdge — подписка одного участника группы на другого
+type GroupEdge struct {
+	SubscriberID uint
+	UserID       uint
+}
+
+// GetGroupRelationships получает одним запросом все подписки между пользователями группы.
+// Подписки на самого себя не возвращаются
+func (r *PostgresSubscriptionRepository) GetGroupRelationships(ctx context.Context, userIDs []uint) ([]GroupEdge, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetGroupRelationships operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var subscriptions []GormSubscription
+	if err := r.db.Select("subscriber_id", "user_id").
+		Where("subscriber_id IN ? AND user_id IN ? AND subscriber_id <> user_id", userIDs, userIDs).
+		Order("subscriber_id, user_id").
+		Find(&subscriptions).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get group relationships", slog.Any("error", err))
+		return nil, err
+	}
+
+	edges := make([]GroupEdge, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		edges = append(edges, GroupEdge{SubscriberID: subscription.SubscriberID, UserID: subscription.UserID})
+	}
+
+	r.logger.InfoContext(ctx, "group relationships fetched successfully", slog.Int("edges", len(edges)))
+	return edges, nil
+}
+
+// SubscriptionRecord представляет подписку при выгрузке всего графа подписок
+type SubscriptionRecord struct {
+	SubscriberID uint      `json:"subscriber_id"`
+	UserID       uint      `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StreamAllSubscriptions выгружает все подписки пачками по возрастанию первичного ключа,
+// передавая каждую пачку в handle. Выгрузка прерывается при отмене контекста или ошибке handle
+func (r *PostgresSubscriptionRepository) StreamAllSubscriptions(ctx context.Context, batchSize int, handle func([]SubscriptionRecord) error) error {
+	var lastID uint
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.ErrorContext(ctx, "StreamAllSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+			return ctx.Err()
+		default:
+		}
+
+		var subscriptions []GormSubscription
+		if err := r.db.Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&subscriptions).Error; err != nil {
+			r.logger.ErrorContext(ctx, "failed to get subscriptions batch", slog.Any("error", err))
+			return err
+		}
+		if len(subscriptions) == 0 {
+			break
+		}
+
+		records := make([]SubscriptionRecord, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			records = append(records, SubscriptionRecord{
+				SubscriberID: subscription.SubscriberID,
+				UserID:       subscription.UserID,
+				CreatedAt:    subscription.CreatedAt,
+			})
+		}
+		if err := handle(records); err != nil {
+			return err
+		}
+
+		lastID = subscriptions[len(subscriptions)-1].ID
+		if len(subscriptions) < batchSize {
+			break
+		}
+	}
+
+	r.logger.InfoContext(ctx, "subscriptions streamed successfully")
+	return nil
+}
+
+// SubscriptionHistoryEntry описывает одну подписку пары пользователей, включая мягко удаленные
+type SubscriptionHistoryEntry struct {
+	ID        uint
+	CreatedAt time.Time
+	DeletedAt *time.Time // Момент отписки (nil — подписка активна)
+}
+
+// GetSubscriptionHistory возвращает все подписки пользователя на другого пользователя, включая мягко удаленные,
+// в порядке создания. Предназначен для разбора обращений поддержки
+func (r *PostgresSubscriptionRepository) GetSubscriptionHistory(ctx context.Context, subscriberID uint, userID uint) ([]SubscriptionHistoryEntry, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetSubscriptionHistory operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var subscriptions []GormSubscription
+	if err := r.db.Unscoped().
+		Where("subscriber_id = ? AND user_id = ?", subscriberID, userID).
+		Order("created_at, id").
+		Find(&subscriptions).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscription history", slog.Any("error", err))
+		return nil, err
+	}
+
+	history := make([]SubscriptionHistoryEntry, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		entry := SubscriptionHistoryEntry{ID: subscription.ID, CreatedAt: subscription.CreatedAt}
+		if subscription.DeletedAt.Valid {
+			deletedAt := subscription.DeletedAt.Time
+			entry.DeletedAt = &deletedAt
+		}
+		history = append(history, entry)
+	}
+
+	r.logger.InfoContext(ctx, "subscription history fetched successfully")
+	return history, nil
+}
+
+// PurgeDeletedSubscriptions окончательно удаляет подписки, мягко удаленные раньше deletedBefore.
+// Удаление выполняется пачками по batchSize строк, чтобы не удерживать блокировки надолго
+func (r *PostgresSubscriptionRepository) PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error) {
+	var purged int64
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.ErrorContext(ctx, "PurgeDeletedSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+			return purged, ctx.Err()
+		default:
+		}
+
+		result := r.db.Exec(
+			`DELETE FROM subscription WHERE id IN (
+				SELECT id FROM subscription
+				WHERE deleted_at IS NOT NULL AND deleted_at < ?
+				LIMIT ?
+			)`,
+			deletedBefore, batchSize,
+		)
+		if result.Error != nil {
+			r.logger.ErrorContext(ctx, "failed to purge deleted subscriptions", slog.Any("error", result.Error))
+			return purged, result.Error
+		}
+
+		purged += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			break
+		}
+	}
+
+	r.logger.InfoContext(ctx, "deleted subscriptions purged successfully", slog.Int64("count", purged))
+	return purged, nil
+}
+
+// DuplicateSubscription описывает пару пользователей, для которой существует несколько действующих подписок
+type DuplicateSubscription struct {
+	SubscriberID uint
+	UserID       uint
+	Count        int64
+}
+
+// FindDuplicateSubscriptions возвращает пары (subscriber_id, user_id), для которых есть больше одной действующей подписки.
+// Используется для проверки данных перед созданием уникального индекса
+func (r *PostgresSubscriptionRepository) FindDuplicateSubscriptions(ctx context.Context) ([]DuplicateSubscription, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "FindDuplicateSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	var duplicates []DuplicateSubscription
+	if err := r.db.Model(&GormSubscription{}).
+		Select("subscriber_id, user_id, COUNT(*) AS count").
+		Group("subscriber_id, user_id").
+		Having("COUNT(*) > 1").
+		Order("subscriber_id, user_id").
+		Scan(&duplicates).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to find duplicate subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "duplicate subscriptions found", slog.Int("pairs", len(duplicates)))
+	return duplicates, nil
+}
+
+// DedupeSubscriptions удаляет дубликаты действующих подписок, оставляя для каждой пары самую раннюю подписку.
+// Дубликаты удаляются окончательно, а не мягко, чтобы не мешать созданию уникального индекса
+func (r *PostgresSubscriptionRepository) DedupeSubscriptions(ctx context.Context) (int64, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "DedupeSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return 0, ctx.Err()
+	default:
+	}
+
+	result := r.db.Exec(
+		`DELETE FROM subscription WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY subscriber_id, user_id ORDER BY created_at, id
+				) AS position
+				FROM subscription
+				WHERE deleted_at IS NULL
+			) ranked
+			WHERE ranked.position > 1
+		)`,
+	)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "failed to dedupe subscriptions", slog.Any("error", result.Error))
+		return 0, result.Error
+	}
+
+	r.logger.InfoContext(ctx, "duplicate subscriptions removed", slog.Int64("count", result.RowsAffected))
+	return result.RowsAffected, nil
+}
+
+// WatchlistItem представляет элемент вотчлиста
+type WatchlistItem struct {
+	MediaID uint   `json:"media_id"`
+	UserID  uint   `json:"user_id"`
+	Title   string `json:"title"`
+	Desc    string `json:"description"`
+}
+
+// ReviewItem представляет элемент отзыва
+type ReviewItem struct {
+	ReviewID uint   `json:"review_id"`
+	UserID   uint   `json:"user_id"`
+	Content  string `json:"content"`
+	Rating   int    `json:"rating"`
+}
+
+// GetWatchlistsBySubscription получает вотчлисты пользователей, на которых подписан пользователь.
+// Подписки обходятся группами по opts.Concurrency; обход прекращается, как только набрано opts.MaxItems элементов.
+// Собранная лента упорядочивается от новых элементов к старым до разбиения на страницы
+func (r *PostgresSubscriptionRepository) GetWatchlistsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*WatchlistFeed, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetWatchlistsBySubscription operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+	subscribedToIDs = feedSourceIDs(userID, subscribedToIDs, opts)
+
+	// Если пользователь ни на кого не подписан, возвращаем пустой список без обращения к внешним сервисам
+	if len(subscribedToIDs) == 0 {
+		r.logger.InfoContext(ctx, "no subscriptions, watchlists feed is empty")
+		return &WatchlistFeed{Items: []*subscription.WatchlistItem{}}, nil
+	}
+
+	dependencies := []*dependencyHealth{r.watchlistHealth, r.mediaHealth, r.userHealth}
+	if opts.Raw {
+		dependencies = dependencies[:1]
+	}
+	if err := r.requireDependencies(ctx, dependencies...); err != nil {
+		return nil, err
+	}
+
+	budget := newCallBudget(opts.CallBudget)
+	fanOutCtx, cancel := withFeedDeadline(ctx, opts.DeadlineMargin)
+	defer cancel()
+	result, err := fanOutFeed(fanOutCtx, subscribedToIDs, opts.Concurrency, opts.MaxItems,
+		func(ctx context.Context, subscribedToID uint, limit int) ([]watchlistEntry, bool, error) {
+			return r.fetchWatchlistEntries(ctx, subscribedToID, limit, opts.Raw, opts.Since, budget)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	sortWatchlistEntries(result.Items)
+	items := make([]*subscription.WatchlistItem, 0, len(result.Items))
+	for _, entry := range result.Items {
+		items = append(items, entry.item)
+	}
+
+	if result.Truncated {
+		r.logger.WarnContext(ctx, "watchlists feed truncated", slog.Int("max_items", opts.MaxItems),
+			slog.Bool("call_budget_exhausted", budget.exhausted()))
+	}
+	if result.Incomplete {
+		r.logger.WarnContext(ctx, "watchlists feed incomplete: deadline exceeded", slog.Int("items", len(result.Items)))
+	}
+
+	r.logger.InfoContext(ctx, "watchlists fetched successfully")
+	return &WatchlistFeed{Items: applyPage(items, opts.Page), Truncated: result.Truncated, Incomplete: result.Incomplete}, nil
+}
+
+// GetReviewsBySubscription получает отзывы пользователей, на которых подписан пользователь.
+// Подписки обходятся группами по opts.Concurrency; обход прекращается, как только набрано opts.MaxItems элементов.
+// Медиа отзывов запрашиваются после обхода, по одному вызову на уникальный ID.
+// Собранная лента упорядочивается по opts.SortBy до разбиения на страницы
+func (r *PostgresSubscriptionRepository) GetReviewsBySubscription(ctx context.Context, userID uint, opts FeedOptions) (*ReviewFeed, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetReviewsBySubscription operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+	subscribedToIDs = feedSourceIDs(userID, subscribedToIDs, opts)
+
+	// Если пользователь ни на кого не подписан, возвращаем пустой список без обращения к внешним сервисам
+	if len(subscribedToIDs) == 0 {
+		r.logger.InfoContext(ctx, "no subscriptions, reviews feed is empty")
+		return &ReviewFeed{Items: []*subscription.ReviewItem{}}, nil
+	}
+
+	if err := r.requireDependencies(ctx, r.reviewHealth, r.mediaHealth, r.userHealth); err != nil {
+		return nil, err
+	}
+
+	budget := newCallBudget(opts.CallBudget)
+	fanOutCtx, cancel := withFeedDeadline(ctx, opts.DeadlineMargin)
+	defer cancel()
+	result, err := fanOutFeed(fanOutCtx, subscribedToIDs, opts.Concurrency, opts.MaxItems,
+		func(ctx context.Context, subscribedToID uint, limit int) ([]reviewEntry, bool, error) {
+			return r.fetchReviewEntries(ctx, subscribedToID, limit, opts.Since, opts.MinRating, budget)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	sortReviewEntries(result.Items, opts.SortBy)
+
+	// Медиа разрешаются одним проходом по уникальным ID всей ленты: отзывы подписок часто ссылаются на одни и те же тайтлы
+	medias, err := r.resolveReviewMedia(fanOutCtx, result.Items, opts.Concurrency, budget)
+	if err != nil {
+		return nil, err
+	}
+	items := buildReviewItems(result.Items, medias)
+	if len(items) < len(result.Items) {
+		if budget.exhausted() {
+			result.Truncated = true
+		} else {
+			result.Incomplete = true
+		}
+	}
+
+	if result.Truncated {
+		r.logger.WarnContext(ctx, "reviews feed truncated", slog.Int("max_items", opts.MaxItems),
+			slog.Bool("call_budget_exhausted", budget.exhausted()))
+	}
+	if result.Incomplete {
+		r.logger.WarnContext(ctx, "reviews feed incomplete: deadline exceeded", slog.Int("items", len(items)))
+	}
+
+	r.logger.InfoContext(ctx, "reviews fetched successfully")
+	pageItems := applyPage(items, opts.Page)
+	return &ReviewFeed{
+		Items:      pageItems,
+		Truncated:  result.Truncated,
+		Incomplete: result.Incomplete,
+		CreatedAt:  reviewCreatedAt(result.Items, pageItems),
+	}, nil
+}
+
+// ActiveSubscription — пользователь из подписок и время его последней активности
+type ActiveSubscription struct {
+	UserID       uint
+	LastActiveAt time.Time // Время последнего отзыва или добавления в вотчлист
+}
+
+// ActiveSubscriptions — подписки, упорядоченные по времени последней активности
+type ActiveSubscriptions struct {
+	Items      []ActiveSubscription
+	Truncated  bool // Обход подписок остановлен по бюджету вызовов, рейтинг построен по части подписок
+	Incomplete bool // Обход подписок прерван по дедлайну запроса, рейтинг построен по части подписок
+}
+
+// GetActiveSubscriptions возвращает не более limit пользователей из подписок, упорядоченных от недавно активных к давно активным.
+// Активность определяется по последнему отзыву и последнему элементу вотчлиста; пользователи без активности не возвращаются.
+// Подписки обходятся с теми же ограничениями параллельности, бюджета вызовов и дедлайна, что и ленты
+func (r *PostgresSubscriptionRepository) GetActiveSubscriptions(ctx context.Context, userID uint, limit int, opts FeedOptions) (*ActiveSubscriptions, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetActiveSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+	if len(subscribedToIDs) == 0 {
+		return &ActiveSubscriptions{Items: []ActiveSubscription{}}, nil
+	}
+
+	if err := r.requireDependencies(ctx, r.reviewHealth, r.watchlistHealth); err != nil {
+		return nil, err
+	}
+
+	budget := newCallBudget(opts.CallBudget)
+	fanOutCtx, cancel := withFeedDeadline(ctx, opts.DeadlineMargin)
+	defer cancel()
+	// Для рейтинга нужна активность всех подписок, поэтому число элементов обхода не ограничивается
+	result, err := fanOutFeed(fanOutCtx, subscribedToIDs, opts.Concurrency, 0,
+		func(ctx context.Context, subscribedToID uint, _ int) ([]ActiveSubscription, bool, error) {
+			return r.fetchLastActivity(ctx, subscribedToID, budget)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	active := result.Items
+	// При равном времени активности порядок определяется ID пользователя, а не временем ответа сервисов
+	sort.SliceStable(active, func(i, j int) bool {
+		if !active[i].LastActiveAt.Equal(active[j].LastActiveAt) {
+			return active[i].LastActiveAt.After(active[j].LastActiveAt)
+		}
+		return active[i].UserID < active[j].UserID
+	})
+	if limit > 0 && len(active) > limit {
+		active = active[:limit]
+	}
+
+	if result.Truncated || result.Incomplete {
+		r.logger.WarnContext(ctx, "active subscriptions ranked by partial activity",
+			slog.Bool("call_budget_exhausted", budget.exhausted()), slog.Bool("deadline_exceeded", result.Incomplete))
+	}
+
+	r.logger.InfoContext(ctx, "active subscriptions fetched successfully", slog.Int("count", len(active)))
+	return &ActiveSubscriptions{Items: active, Truncated: result.Truncated, Incomplete: result.Incomplete}, nil
+}
+
+// InactiveSubscriptions — подписки без активности с заданного момента, упорядоченные от давно активных к недавно активным
+type InactiveSubscriptions struct {
+	Items      []ActiveSubscription // LastActiveAt нулевое, если у пользователя нет активности
+	Truncated  bool                 // Обход подписок остановлен по бюджету вызовов, список построен по части подписок
+	Incomplete bool                 // Обход подписок прерван по дедлайну запроса, список построен по части подписок
+}
+
+// GetInactiveSubscriptions возвращает пользователей из подписок, у которых нет отзывов и элементов вотчлиста,
+// созданных начиная с inactiveSince. Пользователи без активности идут первыми.
+// Подписки обходятся с теми же ограничениями параллельности, бюджета вызовов и дедлайна, что и ленты
+func (r *PostgresSubscriptionRepository) GetInactiveSubscriptions(ctx context.Context, userID uint, inactiveSince time.Time, opts FeedOptions) (*InactiveSubscriptions, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetInactiveSubscriptions operation canceled", slog.Any("error", ctx.Err()))
+		return nil, ctx.Err()
+	default:
+	}
+
+	subscribedToIDs, err := r.GetSubscriptions(ctx, userID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get subscriptions", slog.Any("error", err))
+		return nil, err
+	}
+	if len(subscribedToIDs) == 0 {
+		return &InactiveSubscriptions{Items: []ActiveSubscription{}}, nil
+	}
+
+	if err := r.requireDependencies(ctx, r.reviewHealth, r.watchlistHealth); err != nil {
+		return nil, err
+	}
+
+	budget := newCallBudget(opts.CallBudget)
+	fanOutCtx, cancel := withFeedDeadline(ctx, opts.DeadlineMargin)
+	defer cancel()
+	result, err := fanOutFeed(fanOutCtx, subscribedToIDs, opts.Concurrency, 0,
+		func(ctx context.Context, subscribedToID uint, _ int) ([]ActiveSubscription, bool, error) {
+			activity, truncated, err := r.fetchLastActivity(ctx, subscribedToID, budget)
+			if err != nil || truncated {
+				return nil, truncated, err
+			}
+			if len(activity) == 0 {
+				// Пользователь без активности неактивен при любом inactiveSince
+				return []ActiveSubscription{{UserID: subscribedToID}}, false, nil
+			}
+			if activity[0].LastActiveAt.Before(inactiveSince) {
+				return activity, false, nil
+			}
+			return []ActiveSubscription{}, false, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := result.Items
+	sort.SliceStable(inactive, func(i, j int) bool {
+		if !inactive[i].LastActiveAt.Equal(inactive[j].LastActiveAt) {
+			return inactive[i].LastActiveAt.Before(inactive[j].LastActiveAt)
+		}
+		return inactive[i].UserID < inactive[j].UserID
+	})
+
+	if result.Truncated || result.Incomplete {
+		r.logger.WarnContext(ctx, "inactive subscriptions built from partial activity",
+			slog.Bool("call_budget_exhausted", budget.exhausted()), slog.Bool("deadline_exceeded", result.Incomplete))
+	}
+
+	r.logger.InfoContext(ctx, "inactive subscriptions fetched successfully", slog.Int("count", len(inactive)))
+	return &InactiveSubscriptions{Items: inactive, Truncated: result.Truncated, Incomplete: result.Incomplete}, nil
+}
+
+// fetchLastActivity определяет время последней активности пользователя по его отзывам и вотчлисту.
+// Возвращает пустой список, если у пользователя нет активности с корректной датой
+func (r *PostgresSubscriptionRepository) fetchLastActivity(ctx context.Context, subscribedToID uint, budget *callBudget) ([]ActiveSubscription, bool, error) {
+	var lastActiveAt time.Time
+	observe := func(createdAt string) {
+		// Элементы с некорректной датой не учитываются
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil && t.After(lastActiveAt) {
+			lastActiveAt = t
+		}
+	}
+
+	if !budget.take() {
+		return nil, true, nil
+	}
+	reviewResponse, err := r.reviewClient.GetByUser(ctx, &review.GetByUserRequest{UserId: int64(subscribedToID)})
+	r.reviewHealth.Record(err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get reviews from review service", slog.Any("error", err))
+		return nil, false, &DownstreamError{Service: "review", Method: "GetByUser", Err: err}
+	}
+	for _, reviewProto := range reviewResponse.GetReviews() {
+		observe(reviewProto.GetCreatedAt())
+	}
+
+	if !budget.take() {
+		return nil, true, nil
+	}
+	watchlistResponse, err := r.watchlistClient.GetWatchlist(ctx, &watchlist.GetWatchlistRequest{UserId: int64(subscribedToID)})
+	r.watchlistHealth.Record(err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get watchlist from watchlist service", slog.Any("error", err))
+		return nil, false, &DownstreamError{Service: "watchlist", Method: "GetWatchlist", Err: err}
+	}
+	for _, watchlistItem := range watchlistResponse.GetWatchlists() {
+		observe(watchlistItem.GetCreatedAt())
+	}
+
+	if lastActiveAt.IsZero() {
+		return []ActiveSubscription{}, false, nil
+	}
+	return []ActiveSubscription{{UserID: subscribedToID, LastActiveAt: lastActiveAt}}, false, nil
+}
+
+// watchlistEntry — элемент ленты вотчлистов с датой добавления, по которой лента упорядочивается
+type watchlistEntry struct {
+	item      *subscription.WatchlistItem
+	createdAt time.Time // Дата добавления в вотчлист; нулевая, если сервис вотчлистов вернул некорректную дату
+}
+
+// fetchWatchlistEntries получает не более limit элементов вотчлиста одной подписки (limit < 0 — без ограничения).
+// Если since не нулевое, возвращаются только элементы, добавленные позже since. В режиме raw элементы содержат только ID пользователя и медиа, а сервисы медиа и пользователей не вызываются.
+// Каждый вызов внешнего сервиса списывается с budget; при его исчерпании возвращаются уже собранные элементы
+func (r *PostgresSubscriptionRepository) fetchWatchlistEntries(ctx context.Context, subscribedToID uint, limit int, raw bool, since time.Time, budget *callBudget) ([]watchlistEntry, bool, error) {
+	if !budget.take() {
+		return nil, true, nil
+	}
+	watchlistResponse, err := r.watchlistClient.GetWatchlist(ctx, &watchlist.GetWatchlistRequest{UserId: int64(subscribedToID)})
+	r.watchlistHealth.Record(err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get watchlist from watchlist service", slog.Any("error", err))
+		return nil, false, &DownstreamError{Service: "watchlist", Method: "GetWatchlist", Err: err}
+	}
+
+	watchlistItems := withoutNilItems(ctx, r.logger, "watchlist", watchlistResponse.GetWatchlists())
+	watchlists := make([]watchlistEntry, 0, len(watchlistItems))
+	var username string
+	for _, watchlistItem := range watchlistItems {
+		if !isUnseen(watchlistItem.CreatedAt, since) {
+			continue
+		}
+		if limit >= 0 && len(watchlists) >= limit {
+			return watchlists, true, nil
+		}
+		// Ошибка разбора оставляет нулевую дату, и такой элемент считается самым старым
+		createdAt, _ := time.Parse(time.RFC3339, watchlistItem.CreatedAt)
+		if raw {
+			watchlists = append(watchlists, watchlistEntry{
+				item:      &subscription.WatchlistItem{MediaId: watchlistItem.MediaId, UserId: watchlistItem.UserId},
+				createdAt: createdAt,
+			})
+			continue
+		}
+		if !budget.take() {
+			return watchlists, true, nil
+		}
+		mediaResponse, err := r.mediaClient.GetMediaByID(ctx, &media.GetMediaByIDRequest{Id: watchlistItem.MediaId})
+		r.mediaHealth.Record(err)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to get media info from media service", slog.Any("error", err))
+			return nil, false, &DownstreamError{Service: "media", Method: "GetMediaByID", Err: err}
+		}
+		mediaResponse = r.nonNilMedia(ctx, watchlistItem.MediaId, mediaResponse)
+
+		// Все элементы принадлежат одному пользователю, поэтому имя разрешается один раз
+		if len(watchlists) == 0 {
+			if !budget.take() {
+				return nil, true, nil
+			}
+			username, err = r.usernames.ResolveUsername(ctx, subscribedToID)
+			if err != nil {
+				return nil, false, err
+			}
+			username = r.options.UsernameNormalization.apply(username)
+		}
+
+		// Год и постер медиа не передаются: в сообщении subscription.WatchlistItem пока нет
+		// соответствующих полей (в отличие от media_year в ReviewItem)
+		watchlistItemInfo := &subscription.WatchlistItem{
+			MediaId:     watchlistItem.MediaId,
+			UserId:      watchlistItem.UserId,
+			UserName:    username,
+			Title:       mediaResponse.NameEn,
+			Description: mediaResponse.Description,
+		}
+		watchlists = append(watchlists, watchlistEntry{item: watchlistItemInfo, createdAt: createdAt})
+	}
+
+	return watchlists, false, nil
+}
+
+// reviewEntry — отзыв подписки с именем автора; медиа отзыва разрешается после обхода всех подписок
+type reviewEntry struct {
+	review    *review.Review
+	username  string
+	createdAt time.Time // Дата создания отзыва; нулевая, если сервис отзывов вернул некорректную дату
+}
+
+// fetchReviewEntries получает не более limit отзывов одной подписки (limit < 0 — без ограничения).
+// Если since не нулевое, возвращаются только отзывы, написанные позже since; отзывы с оценкой ниже minRating не возвращаются.
+// Каждый вызов внешнего сервиса списывается с budget; при его исчерпании отзывы подписки не возвращаются
+func (r *PostgresSubscriptionRepository) fetchReviewEntries(ctx context.Context, subscribedToID uint, limit int, since time.Time, minRating int32, budget *callBudget) ([]reviewEntry, bool, error) {
+	if !budget.take() {
+		return nil, true, nil
+	}
+	reviewResponse, err := r.reviewClient.GetByUser(ctx, &review.GetByUserRequest{UserId: int64(subscribedToID)})
+	r.reviewHealth.Record(err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get reviews from review service", slog.Any("error", err))
+		return nil, false, &DownstreamError{Service: "review", Method: "GetByUser", Err: err}
+	}
+	reviewProtos := withoutNilItems(ctx, r.logger, "review", reviewResponse.GetReviews())
+	if !since.IsZero() || minRating > 0 {
+		reviewProtos = slices.DeleteFunc(slices.Clone(reviewProtos), func(reviewProto *review.Review) bool {
+			return !isUnseen(reviewProto.CreatedAt, since) || reviewProto.Rating < minRating
+		})
+	}
+	if len(reviewProtos) == 0 {
+		return []reviewEntry{}, false, nil
+	}
+
+	// Все отзывы принадлежат одному пользователю, поэтому имя разрешается один раз
+	if !budget.take() {
+		return nil, true, nil
+	}
+	username, err := r.usernames.ResolveUsername(ctx, subscribedToID)
+	if err != nil {
+		return nil, false, err
+	}
+	username = r.options.UsernameNormalization.apply(username)
+
+	truncated := false
+	if limit >= 0 && len(reviewProtos) > limit {
+		reviewProtos = reviewProtos[:limit]
+		truncated = true
+	}
+
+	entries := make([]reviewEntry, 0, len(reviewProtos))
+	for _, reviewProto := range reviewProtos {
+		// Ошибка разбора оставляет нулевую дату, и такой отзыв считается самым старым
+		createdAt, _ := time.Parse(time.RFC3339, reviewProto.CreatedAt)
+		entries = append(entries, reviewEntry{review: reviewProto, username: username, createdAt: createdAt})
+	}
+	return entries, truncated, nil
+}
+
+// resolveReviewMedia получает медиа всех отзывов, запрашивая каждый уникальный ID один раз.
+// Вызовы выполняются параллельно, не более concurrency одновременно, и списываются с budget в порядке
+// первого упоминания медиа в ленте. При исчерпании бюджета или дедлайне ctx возвращаются уже полученные медиа
+func (r *PostgresSubscriptionRepository) resolveReviewMedia(ctx context.Context, entries []reviewEntry, concurrency int, budget *callBudget) (map[int64]*media.Media, error) {
+	mediaIDs := make([]int64, 0, len(entries))
+	seen := make(map[int64]struct{}, len(entries))
+	for _, entry := range entries {
+		if _, ok := seen[entry.review.MediaId]; ok {
+			continue
+		}
+		seen[entry.review.MediaId] = struct{}{}
+		mediaIDs = append(mediaIDs, entry.review.MediaId)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	medias := make(map[int64]*media.Media, len(mediaIDs))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for _, mediaID := range mediaIDs {
+		if !budget.take() {
+			break
+		}
+		group.Go(func() error {
+			mediaResponse, err := r.mediaClient.GetMediaByID(groupCtx, &media.GetMediaByIDRequest{Id: mediaID})
+			r.mediaHealth.Record(err)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to get media info from media service", slog.Any("error", err))
+				return &DownstreamError{Service: "media", Method: "GetMediaByID", Err: err}
+			}
+			mediaResponse = r.nonNilMedia(ctx, mediaID, mediaResponse)
+			mu.Lock()
+			medias[mediaID] = mediaResponse
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, err
+	}
+
+	r.logger.DebugContext(ctx, "review media resolved", slog.Int("reviews", len(entries)), slog.Int("distinct_media", len(mediaIDs)))
+	return medias, nil
+}
+
+// withoutNilItems возвращает элементы ответа сервиса service без пустых (nil) элементов, о которых пишется предупреждение.
+// Если пустых элементов нет, возвращается исходный срез
+func withoutNilItems[T any](ctx context.Context, logger *slog.Logger, service string, items []*T) []*T {
+	if !slices.Contains(items, nil) {
+		return items
+	}
+	nonNil := slices.DeleteFunc(slices.Clone(items), func(item *T) bool { return item == nil })
+	logger.WarnContext(ctx, "downstream response contains empty items", slog.String("service", service),
+		slog.Int("skipped", len(items)-len(nonNil)))
+	return nonNil
+}
+
+// nonNilMedia возвращает mediaResponse или, если сервис медиа вернул пустой ответ, медиа без названия и года,
+// чтобы некорректный ответ не прерывал построение ленты
+func (r *PostgresSubscriptionRepository) nonNilMedia(ctx context.Context, mediaID int64, mediaResponse *media.Media) *media.Media {
+	if mediaResponse != nil {
+		return mediaResponse
+	}
+	r.logger.WarnContext(ctx, "media service returned no media", slog.Int64("media_id", mediaID))
+	return &media.Media{Id: mediaID}
+}
+
+// buildReviewItems собирает элементы ленты отзывов. Сборка останавливается на первом отзыве,
+// медиа которого не было получено, чтобы лента оставалась непрерывным префиксом
+func buildReviewItems(entries []reviewEntry, medias map[int64]*media.Media) []*subscription.ReviewItem {
+	items := make([]*subscription.ReviewItem, 0, len(entries))
+	for _, entry := range entries {
+		mediaResponse, ok := medias[entry.review.MediaId]
+		if !ok {
+			break
+		}
+		items = append(items, &subscription.ReviewItem{
+			ReviewId:  entry.review.Id,
+			UserId:    entry.review.UserId,
+			UserName:  entry.username,
+			Content:   entry.review.Content,
+			Rating:    entry.review.Rating,
+			MediaName: mediaResponse.NameEn,
+			MediaYear: mediaResponse.Year,
+		})
+	}
+	return items
+}
+
+// reviewCreatedAt возвращает даты создания отзывов из items по ID отзыва
+func reviewCreatedAt(entries []reviewEntry, items []*subscription.ReviewItem) map[int64]time.Time {
+	inFeed := make(map[int64]bool, len(items))
+	for _, item := range items {
+		inFeed[item.ReviewId] = true
+	}
+	createdAt := make(map[int64]time.Time, len(items))
+	for _, entry := range entries {
+		if inFeed[entry.review.Id] && !entry.createdAt.IsZero() {
+			createdAt[entry.review.Id] = entry.createdAt
+		}
+	}
+	return createdAt
 }
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// CachedSubscriptionCheckRepository кэширует результаты IsSubscribed. Наличие подписки хранится ttl,
+// отсутствие — отдельно заданное, обычно более короткое negativeTTL (0 — отсутствие не кэшируется).
+// Проверки подписчика сбрасываются, когда его подписки меняются через этот репозиторий, поэтому только что
+// созданная подписка сразу видна; изменения, сделанные другими экземплярами сервиса, видны по истечении срока хранения.
+// Остальные методы передаются репозиторию без изменений
+type CachedSubscriptionCheckRepository struct {
+	SubscriptionRepository
+	positive *feedCache[bool]
+	negative *feedCache[bool]
+}
+
+// NewCachedSubscriptionCheckRepository создает новый экземпляр CachedSubscriptionCheckRepository поверх другого репозитория
+func NewCachedSubscriptionCheckRepository(next SubscriptionRepository, ttl time.Duration, negativeTTL time.Duration) *CachedSubscriptionCheckRepository {
+	r := &CachedSubscriptionCheckRepository{
+		SubscriptionRepository: next,
+		positive:               newFeedCache[bool]("subscription_checks", ttl, 0),
+	}
+	if negativeTTL > 0 {
+		r.negative = newFeedCache[bool]("subscription_checks_negative", negativeTTL, 0)
+	}
+	return r
+}
+
+// IsSubscribed возвращает результат проверки подписки из кэша или проверяет ее заново
+func (r *CachedSubscriptionCheckRepository) IsSubscribed(ctx context.Context, subscriberID uint, userID uint) (bool, error) {
+	key := strconv.FormatUint(uint64(userID), 10)
+	if _, ok := r.positive.get(subscriberID, key); ok {
+		return true, nil
+	}
+	if r.negative != nil {
+		if _, ok := r.negative.get(subscriberID, key); ok {
+			return false, nil
+		}
+	}
+
+	isSubscribed, err := r.SubscriptionRepository.IsSubscribed(ctx, subscriberID, userID)
+	if err != nil {
+		return false, err
+	}
+	if isSubscribed {
+		r.positive.set(subscriberID, key, true)
+	} else if r.negative != nil {
+		r.negative.set(subscriberID, key, false)
+	}
+	return isSubscribed, nil
+}
+
+// Subscribe добавляет подписку и сбрасывает проверки подписчика, в том числе закэшированное отсутствие подписки
+func (r *CachedSubscriptionCheckRepository) Subscribe(ctx context.Context, subscriberID uint, userID uint) (CreatedSubscription, error) {
+	created, err := r.SubscriptionRepository.Subscribe(ctx, subscriberID, userID)
+	if err == nil {
+		r.invalidate(subscriberID)
+	}
+	return created, err
+}
+
+// Unsubscribe удаляет подписку и сбрасывает проверки подписчика
+func (r *CachedSubscriptionCheckRepository) Unsubscribe(ctx context.Context, subscriberID uint, userID uint) error {
+	err := r.SubscriptionRepository.Unsubscribe(ctx, subscriberID, userID)
+	if err == nil {
+		r.invalidate(subscriberID)
+	}
+	return err
+}
+
+// UnsubscribeBatch удаляет подписки и сбрасывает проверки подписчика
+func (r *CachedSubscriptionCheckRepository) UnsubscribeBatch(ctx context.Context, subscriberID uint, userIDs []uint) ([]uint, error) {
+	removedIDs, err := r.SubscriptionRepository.UnsubscribeBatch(ctx, subscriberID, userIDs)
+	if err == nil && len(removedIDs) > 0 {
+		r.invalidate(subscriberID)
+	}
+	return removedIDs, err
+}
+
+// MergeUser переносит подписки и сбрасывает проверки обоих аккаунтов как подписчиков.
+// Проверки подписок других пользователей на эти аккаунты обновятся по истечении срока хранения
+func (r *CachedSubscriptionCheckRepository) MergeUser(ctx context.Context, fromID uint, toID uint) (MergeResult, error) {
+	result, err := r.SubscriptionRepository.MergeUser(ctx, fromID, toID)
+	if err == nil {
+		r.invalidate(fromID)
+		r.invalidate(toID)
+	}
+	return result, err
+}
+
+// BulkSubscribeFollowersTo подписывает подписчиков на новый аккаунт и сбрасывает их проверки
+func (r *CachedSubscriptionCheckRepository) BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint, batchSize int) ([]uint, error) {
+	subscribedIDs, err := r.SubscriptionRepository.BulkSubscribeFollowersTo(ctx, oldUserID, newUserID, followerIDs, batchSize)
+	// Часть пачек могла быть записана до ошибки
+	for _, subscribedID := range subscribedIDs {
+		r.invalidate(subscribedID)
+	}
+	return subscribedIDs, err
+}
+
+// invalidate сбрасывает все закэшированные проверки подписчика
+func (r *CachedSubscriptionCheckRepository) invalidate(subscriberID uint) {
+	r.positive.invalidate(subscriberID)
+	if r.negative != nil {
+		r.negative.invalidate(subscriberID)
+	}
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/watchlist-kata/protos/user"
+
+	"github.com/watchlist-kata/subscription/internal/metrics"
+)
+
+// UsernameResolver получает имя пользователя по его ID.
+// Пакетная реализация не предусмотрена: сервис пользователей не поддерживает получение нескольких пользователей одним вызовом
+type UsernameResolver interface {
+	ResolveUsername(ctx context.Context, userID uint) (string, error)
+}
+
+// directUsernameResolver получает имя пользователя вызовом сервиса пользователей
+type directUsernameResolver struct {
+	client user.UserServiceClient
+	health *dependencyHealth
+	logger *slog.Logger
+}
+
+// newDirectUsernameResolver создает новый экземпляр directUsernameResolver
+func newDirectUsernameResolver(client user.UserServiceClient, health *dependencyHealth, logger *slog.Logger) *directUsernameResolver {
+	return &directUsernameResolver{
+		client: client,
+		health: health,
+		logger: logger,
+	}
+}
+
+// ResolveUsername получает имя пользователя из сервиса пользователей.
+// Если сервис вернул ответ без пользователя, возвращается пустое имя
+func (r *directUsernameResolver) ResolveUsername(ctx context.Context, userID uint) (string, error) {
+	userResponse, err := r.client.GetByID(ctx, &user.GetUserRequest{Id: int64(userID)})
+	r.health.Record(err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to get user info from user service", slog.Any("error", err))
+		return "", &DownstreamError{Service: "user", Method: "GetByID", Err: err}
+	}
+	// Ответ без пользователя не должен ронять построение ленты: имя остается пустым
+	if userResponse.GetUser() == nil {
+		r.logger.WarnContext(ctx, "user service returned no user", slog.Uint64("user_id", uint64(userID)))
+		return "", nil
+	}
+	return userResponse.GetUser().GetUsername(), nil
+}
+
+// usernameCacheName — имя кэша имен пользователей в метриках
+const usernameCacheName = "usernames"
+
+// cachedUsername — имя пользователя в кэше и момент истечения его срока хранения
+type cachedUsername struct {
+	username  string
+	expiresAt time.Time
+}
+
+// CachedUsernameResolver кэширует имена пользователей на время ttl. Ошибки не кэшируются
+type CachedUsernameResolver struct {
+	next    UsernameResolver
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uint]cachedUsername
+}
+
+// NewCachedUsernameResolver создает новый экземпляр CachedUsernameResolver поверх другого резолвера
+func NewCachedUsernameResolver(next UsernameResolver, ttl time.Duration) *CachedUsernameResolver {
+	return &CachedUsernameResolver{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[uint]cachedUsername),
+	}
+}
+
+// ResolveUsername возвращает имя пользователя из кэша или получает его у следующего резолвера
+func (r *CachedUsernameResolver) ResolveUsername(ctx context.Context, userID uint) (string, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	entry, ok := r.entries[userID]
+	if ok && now.Before(entry.expiresAt) {
+		r.mu.Unlock()
+		metrics.RecordCacheHit(usernameCacheName, ttlUsed(entry.expiresAt, now, r.ttl))
+		return entry.username, nil
+	}
+	if ok {
+		delete(r.entries, userID)
+		metrics.RecordCacheEvictions(usernameCacheName, metrics.EvictionExpired, 1)
+	}
+	r.mu.Unlock()
+	metrics.RecordCacheMiss(usernameCacheName)
+
+	username, err := r.next.ResolveUsername(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.entries[userID] = cachedUsername{username: username, expiresAt: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return username, nil
+}
+
+// UsernameNormalization задает преобразование имен пользователей в элементах ленты
+type UsernameNormalization string
+
+// Режимы нормализации имен пользователей
+const (
+	// UsernameAsIs оставляет имя без изменений (по умолчанию)
+	UsernameAsIs UsernameNormalization = "none"
+	// UsernameTrim удаляет пробельные символы в начале и конце имени
+	UsernameTrim UsernameNormalization = "trim"
+	// UsernameLower удаляет пробельные символы в начале и конце имени и приводит его к нижнему регистру
+	UsernameLower UsernameNormalization = "lower"
+)
+
+// ParseUsernameNormalization проверяет и возвращает режим нормализации имен пользователей
+func ParseUsernameNormalization(value string) (UsernameNormalization, error) {
+	switch normalization := UsernameNormalization(value); normalization {
+	case UsernameAsIs, UsernameTrim, UsernameLower:
+		return normalization, nil
+	default:
+		return "", fmt.Errorf("unknown username normalization: %q", value)
+	}
+}
+
+// apply возвращает нормализованное имя пользователя
+func (n UsernameNormalization) apply(username string) string {
+	switch n {
+	case UsernameTrim:
+		return strings.TrimSpace(username)
+	case UsernameLower:
+		return strings.ToLower(strings.TrimSpace(username))
+	default:
+		return username
+	}
+}
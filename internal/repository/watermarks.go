@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// MarkFeedSeen сохраняет момент, до которого пользователь просмотрел ленту.
+// Отметка только сдвигается вперед: более ранний момент, например из повторного запроса, ее не меняет
+func (r *PostgresSubscriptionRepository) MarkFeedSeen(ctx context.Context, userID uint, seenAt time.Time) error {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "MarkFeedSeen operation canceled", slog.Any("error", ctx.Err()))
+		return ctx.Err()
+	default:
+	}
+
+	if err := r.db.Exec(
+		`INSERT INTO feed_watermarks (user_id, last_seen_at, updated_at)
+		VALUES (?, ?, now())
+		ON CONFLICT (user_id) DO UPDATE
+		SET last_seen_at = GREATEST(feed_watermarks.last_seen_at, EXCLUDED.last_seen_at), updated_at = now()`,
+		userID, seenAt,
+	).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to mark feed seen", slog.Any("error", err))
+		return err
+	}
+
+	r.logger.InfoContext(ctx, "feed marked seen successfully")
+	return nil
+}
+
+// GetFeedWatermark возвращает момент, до которого пользователь просмотрел ленту,
+// или нулевое время, если пользователь еще не отмечал ленту просмотренной
+func (r *PostgresSubscriptionRepository) GetFeedWatermark(ctx context.Context, userID uint) (time.Time, error) {
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetFeedWatermark operation canceled", slog.Any("error", ctx.Err()))
+		return time.Time{}, ctx.Err()
+	default:
+	}
+
+	var lastSeenAt []time.Time
+	if err := r.db.Table("feed_watermarks").
+		Where("user_id = ?", userID).
+		Pluck("last_seen_at", &lastSeenAt).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get feed watermark", slog.Any("error", err))
+		return time.Time{}, err
+	}
+	if len(lastSeenAt) == 0 {
+		return time.Time{}, nil
+	}
+	return lastSeenAt[0], nil
+}
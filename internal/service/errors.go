@@ -0,0 +1,21 @@
+package service
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InvalidArgumentError возвращает статус InvalidArgument с описанием нарушения для поля запроса,
+// чтобы клиенты могли сопоставить ошибку с полем формы
+func InvalidArgumentError(message string, field string, description string) error {
+	st, err := status.New(codes.InvalidArgument, message).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: description},
+		},
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
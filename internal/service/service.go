@@ -2,38 +2,168 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
+	"unicode/utf8"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/watchlist-kata/protos/subscription"
+
+	"github.com/watchlist-kata/subscription/internal/events"
 	"github.com/watchlist-kata/subscription/internal/repository"
 )
 
 // SubscriptionService представляет сервис для работы с подписками
 type SubscriptionService interface {
-	Subscribe(ctx context.Context, subscriberID uint, subscribeToID uint) error
+	Subscribe(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.CreatedSubscription, error)
 	Unsubscribe(ctx context.Context, subscriberID uint, subscribeToID uint) error
+	UnsubscribeBatch(ctx context.Context, subscriberID uint, targetIDs []uint) (int64, error)
+	MergeUser(ctx context.Context, fromID uint, toID uint) (repository.MergeResult, error)
+	GetFollowersForMigration(ctx context.Context, oldUserID uint, handle func([]uint) error) error
+	BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint) (int64, error)
+	FindDuplicateSubscriptions(ctx context.Context) ([]repository.DuplicateSubscription, error)
+	DedupeSubscriptions(ctx context.Context) (int64, error)
 	GetSubscriptions(ctx context.Context, userID uint) ([]uint, error)
 	GetSubscribers(ctx context.Context, userID uint) ([]uint, error)
+	GetSubscribersWithUsernames(ctx context.Context, userID uint, page repository.Page) ([]repository.UserSummary, error)
+	GetSubscribersSnapshot(ctx context.Context, userID uint, cursor repository.SnapshotCursor, limit int) (repository.SnapshotPage, error)
+	GetMutualSubscribers(ctx context.Context, userID uint, page repository.Page, withUsernames bool) (repository.MutualSubscribers, error)
+	CountSubscribersSince(ctx context.Context, userID uint, since time.Time) (int64, error)
+	GetProfileConnections(ctx context.Context, userID uint, page repository.Page) (repository.ProfileConnections, error)
+	GetSubscriptionGrowth(ctx context.Context, userID uint, from time.Time, to time.Time, bucket repository.GrowthBucket) ([]repository.GrowthPoint, error)
+	GetNonReciprocalFollowers(ctx context.Context, userID uint, page repository.Page) ([]uint, error)
+	GetNonFollowingBack(ctx context.Context, userID uint, page repository.Page) ([]uint, error)
+	GetSubscriptionsWithMutualFlag(ctx context.Context, userID uint, page repository.Page) ([]repository.SubscriptionWithMutual, error)
+	CountCommonSubscriptions(ctx context.Context, firstUserID uint, secondUserID uint) (int64, error)
+	GetFollowedByUsers(ctx context.Context, followerIDs []uint, page repository.Page) ([]repository.FollowedUser, error)
 	IsSubscribed(ctx context.Context, subscriberID uint, subscribeToID uint) (bool, error)
-	GetWatchlistsBySubscription(ctx context.Context, userID uint) ([]*subscription.WatchlistItem, error)
-	GetReviewsBySubscription(ctx context.Context, userID uint) ([]*subscription.ReviewItem, error)
+	GetSubscriptionDetail(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.SubscriptionDetail, error)
+	GetSubscriptionStatus(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.SubscriptionStatus, error)
+	GetProfileSummary(ctx context.Context, viewerID uint, profileID uint) (repository.ProfileSummary, error)
+	AreConnected(ctx context.Context, firstUserID uint, secondUserID uint) (bool, error)
+	GetRelationships(ctx context.Context, viewerID uint, targetIDs []uint) (map[uint]repository.Relationship, error)
+	FindMissingUsers(ctx context.Context, userIDs []uint) ([]uint, error)
+	GetGroupRelationships(ctx context.Context, userIDs []uint) ([]repository.GroupEdge, error)
+	GetSubscriptionHistory(ctx context.Context, subscriberID uint, subscribeToID uint) ([]repository.SubscriptionHistoryEntry, error)
+	StreamAllSubscriptions(ctx context.Context, batchSize int, handle func([]repository.SubscriptionRecord) error) error
+	MarkFeedSeen(ctx context.Context, userID uint, seenAt time.Time) error
+	GetWatchlistsBySubscription(ctx context.Context, userID uint, params FeedParams) (*repository.WatchlistFeed, error)
+	GetReviewsBySubscription(ctx context.Context, userID uint, params FeedParams) (*repository.ReviewFeed, error)
+	GetFeedsForUsers(ctx context.Context, userIDs []uint) (map[uint]*repository.ReviewFeed, error)
+	GetActiveSubscriptions(ctx context.Context, userID uint, limit int) (*repository.ActiveSubscriptions, error)
+	GetInactiveSubscriptions(ctx context.Context, userID uint, inactiveSince time.Time) (*repository.InactiveSubscriptions, error)
+	WatchSubscriptionChanges(ctx context.Context, userID uint, handle func(events.SubscriptionEvent) error) error
+}
+
+// FeedParams содержит параметры запроса ленты
+type FeedParams struct {
+	Page         repository.Page      // Страница ленты; нулевой размер означает всю ленту в пределах FeedMaxItems
+	ForceRefresh bool                 // Построить ленту заново в обход кэша
+	IncludeSelf  bool                 // Добавить в ленту активность самого пользователя
+	Raw          bool                 // Вернуть ленту вотчлистов без названий медиа и имен пользователей
+	Shard        repository.FeedShard // Часть подписок, из которой строится лента (нулевое значение — все подписки)
+	SortBy       repository.FeedSort  // Порядок ленты отзывов
+	Unseen       bool                 // Вернуть только элементы, появившиеся после сохраненной отметки просмотра ленты
+	// MaxContentLength ограничивает длину текста отзывов в символах; более длинный текст обрезается
+	// с многоточием (0 — без обрезки)
+	MaxContentLength int
+	MinRating        int32 // Минимальная оценка отзывов в ленте (0 — все отзывы)
+}
+
+// Options содержит настройки поведения сервиса
+type Options struct {
+	DefaultPageSize int // Размер страницы, если он не задан в запросе
+	MaxPageSize     int // Максимальный размер страницы
+	// SelfSubscribePolicy определяет исход подписки на самого себя (по умолчанию отклоняется)
+	SelfSubscribePolicy SelfSubscribePolicy
+	// FeedMaxItems ограничивает общее число элементов ленты (0 — без ограничения)
+	FeedMaxItems int
+	// IdempotentUnsubscribe делает отписку от несуществующей подписки успешной вместо NotFound
+	IdempotentUnsubscribe bool
+	// FeedCallBudget ограничивает число вызовов внешних сервисов при построении одной ленты (0 — без ограничения)
+	FeedCallBudget int
+	// FeedDeadlineMargin — запас до дедлайна запроса, за который построение ленты прекращается
+	// и возвращается уже собранная часть (0 — без запаса)
+	FeedDeadlineMargin time.Duration
+	// FeedConcurrency задает число подписок, обрабатываемых одновременно при построении ленты (1 — последовательно)
+	FeedConcurrency int
+	// WatchBufferSize — число событий, ожидающих отправки одному наблюдателю за изменениями подписок
+	WatchBufferSize int
+}
+
+// SelfSubscribePolicy задает исход подписки пользователя на самого себя
+type SelfSubscribePolicy string
+
+// Исходы подписки на самого себя
+const (
+	// SelfSubscribeReject отклоняет подписку с кодом InvalidArgument (по умолчанию)
+	SelfSubscribeReject SelfSubscribePolicy = "reject"
+	// SelfSubscribeIgnore завершает запрос успешно, не создавая подписку
+	SelfSubscribeIgnore SelfSubscribePolicy = "ignore"
+	// SelfSubscribeAllow создает подписку на самого себя
+	SelfSubscribeAllow SelfSubscribePolicy = "allow"
+)
+
+// ParseSelfSubscribePolicy проверяет и возвращает исход подписки на самого себя. Пустая строка означает reject
+func ParseSelfSubscribePolicy(value string) (SelfSubscribePolicy, error) {
+	switch policy := SelfSubscribePolicy(value); policy {
+	case "":
+		return SelfSubscribeReject, nil
+	case SelfSubscribeReject, SelfSubscribeIgnore, SelfSubscribeAllow:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown self-subscribe policy: %q", value)
+	}
+}
+
+// EventPublisher публикует доменные события подписок
+type EventPublisher interface {
+	PublishSubscriptionEvent(ctx context.Context, event events.SubscriptionEvent) error
 }
 
 // subscriptionService реализует SubscriptionService
 type subscriptionService struct {
-	repo   repository.SubscriptionRepository
-	logger *slog.Logger
+	repo      repository.SubscriptionRepository
+	logger    *slog.Logger
+	publisher EventPublisher
+	changes   *events.ChangeHub
+	options   Options
 }
 
-// NewSubscriptionService создает новый экземпляр SubscriptionService
-func NewSubscriptionService(repo repository.SubscriptionRepository, logger *slog.Logger) SubscriptionService {
+// NewSubscriptionService создает новый экземпляр SubscriptionService.
+// publisher может быть nil, тогда доменные события не публикуются
+func NewSubscriptionService(repo repository.SubscriptionRepository, logger *slog.Logger, publisher EventPublisher, options Options) SubscriptionService {
 	return &subscriptionService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		logger:    logger,
+		publisher: publisher,
+		changes:   events.NewChangeHub(options.WatchBufferSize),
+		options:   options,
+	}
+}
+
+// publishEvent передает доменное событие наблюдателям и публикует его; ошибка публикации не прерывает обработку запроса
+func (s *subscriptionService) publishEvent(ctx context.Context, eventType string, subscriberID uint, userID uint) {
+	event := events.SubscriptionEvent{
+		Type:         eventType,
+		SubscriberID: subscriberID,
+		UserID:       userID,
+		OccurredAt:   time.Now(),
+	}
+	s.changes.Notify(event)
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.PublishSubscriptionEvent(ctx, event); err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish subscription event", slog.String("type", eventType), slog.Any("error", err))
 	}
 }
 
@@ -47,36 +177,99 @@ func (s *subscriptionService) checkContextCancelled(ctx context.Context, method
 	}
 }
 
-// Subscribe добавляет подписку пользователя на другого пользователя
-func (s *subscriptionService) Subscribe(ctx context.Context, subscriberID uint, subscribeToID uint) error {
+// normalizePage ограничивает размер страницы максимальным значением и подставляет значение по умолчанию,
+// если размер не задан или отрицателен
+func (s *subscriptionService) normalizePage(page repository.Page) repository.Page {
+	if page.Limit <= 0 {
+		page.Limit = s.options.DefaultPageSize
+	}
+	if page.Limit > s.options.MaxPageSize {
+		page.Limit = s.options.MaxPageSize
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	return page
+}
+
+// feedOptions формирует параметры построения ленты с учетом ограничений сервиса
+func (s *subscriptionService) feedOptions(params FeedParams) repository.FeedOptions {
+	page := params.Page
+	if page.Limit > 0 {
+		page = s.normalizePage(page)
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	return repository.FeedOptions{
+		MaxItems:       s.options.FeedMaxItems,
+		Page:           page,
+		Concurrency:    s.options.FeedConcurrency,
+		CallBudget:     s.options.FeedCallBudget,
+		ForceRefresh:   params.ForceRefresh,
+		DeadlineMargin: s.options.FeedDeadlineMargin,
+		SortBy:         params.SortBy,
+		IncludeSelf:    params.IncludeSelf,
+		Raw:            params.Raw,
+		Shard:          params.Shard,
+		MinRating:      params.MinRating,
+	}
+}
+
+// Subscribe добавляет подписку пользователя на другого пользователя и возвращает ID и время создания подписки.
+// Возможные коды ошибок: Canceled — запрос отменен; InvalidArgument — подписка на самого себя при политике reject;
+// NotFound — пользователь, на которого подписываются, не существует; AlreadyExists — подписка уже есть;
+// Unavailable — сервис пользователей недоступен; Internal — ошибка базы данных или внешнего сервиса
+func (s *subscriptionService) Subscribe(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.CreatedSubscription, error) {
 	if err := s.checkContextCancelled(ctx, "Subscribe"); err != nil {
-		return status.Error(codes.Canceled, err.Error())
+		return repository.CreatedSubscription{}, status.Error(codes.Canceled, err.Error())
 	}
 
-	// Проверка, что пользователь не подписывается сам на себя
+	// Подписка на самого себя обрабатывается согласно настройкам; решение принимается здесь,
+	// чтобы gRPC и любой другой транспорт вели себя одинаково
 	if subscriberID == subscribeToID {
-		s.logger.WarnContext(ctx, "cannot subscribe to yourself")
-		return status.Errorf(codes.InvalidArgument, "Cannot subscribe to yourself")
+		switch s.options.SelfSubscribePolicy {
+		case SelfSubscribeAllow:
+		case SelfSubscribeIgnore:
+			s.logger.InfoContext(ctx, "self-subscribe ignored")
+			return repository.CreatedSubscription{}, nil
+		default:
+			s.logger.WarnContext(ctx, "cannot subscribe to yourself")
+			return repository.CreatedSubscription{}, InvalidArgumentError("Cannot subscribe to yourself", "subscribe_to_id", "must differ from subscriber_id")
+		}
+	}
+
+	// Проверка, существует ли пользователь, на которого подписываются
+	exists, err := s.repo.UserExists(ctx, subscribeToID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check user existence", slog.Any("error", err))
+		return repository.CreatedSubscription{}, feedError(fmt.Sprintf("Failed to check user existence: %v", err), err)
+	}
+	if !exists {
+		s.logger.WarnContext(ctx, "user to subscribe to does not exist")
+		return repository.CreatedSubscription{}, status.Errorf(codes.NotFound, "User to subscribe to does not exist")
 	}
 
 	// Проверка, существует ли уже такая подписка
 	isSubscribed, err := s.IsSubscribed(ctx, subscriberID, subscribeToID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to check subscription", slog.Any("error", err))
-		return status.Errorf(codes.Internal, "Failed to check subscription: %v", err)
+		return repository.CreatedSubscription{}, status.Errorf(codes.Internal, "Failed to check subscription: %v", err)
 	}
 	if isSubscribed {
 		s.logger.WarnContext(ctx, "subscription already exists")
-		return status.Errorf(codes.AlreadyExists, "Subscription already exists")
+		return repository.CreatedSubscription{}, status.Errorf(codes.AlreadyExists, "Subscription already exists")
 	}
 
-	if err := s.repo.Subscribe(ctx, subscriberID, subscribeToID); err != nil {
+	created, err := s.repo.Subscribe(ctx, subscriberID, subscribeToID)
+	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to create subscription", slog.Any("error", err))
-		return status.Errorf(codes.Internal, "Failed to create subscription: %v", err)
+		return repository.CreatedSubscription{}, status.Errorf(codes.Internal, "Failed to create subscription: %v", err)
 	}
 
+	s.publishEvent(ctx, events.SubscriptionCreated, subscriberID, subscribeToID)
 	s.logger.InfoContext(ctx, "subscription created successfully")
-	return nil
+	return created, nil
 }
 
 // Unsubscribe удаляет подписку пользователя
@@ -92,6 +285,10 @@ func (s *subscriptionService) Unsubscribe(ctx context.Context, subscriberID uint
 		return status.Errorf(codes.Internal, "Failed to check subscription: %v", err)
 	}
 	if !isSubscribed {
+		if s.options.IdempotentUnsubscribe {
+			s.logger.InfoContext(ctx, "subscription does not exist, nothing to unsubscribe")
+			return nil
+		}
 		s.logger.WarnContext(ctx, "subscription does not exist")
 		return status.Errorf(codes.NotFound, "Subscription does not exist")
 	}
@@ -101,10 +298,143 @@ func (s *subscriptionService) Unsubscribe(ctx context.Context, subscriberID uint
 		return status.Errorf(codes.Internal, "Failed to delete subscription: %v", err)
 	}
 
+	s.publishEvent(ctx, events.SubscriptionDeleted, subscriberID, subscribeToID)
 	s.logger.InfoContext(ctx, "subscription deleted successfully")
 	return nil
 }
 
+// UnsubscribeBatch удаляет подписки пользователя на несколько пользователей и возвращает число удаленных подписок
+func (s *subscriptionService) UnsubscribeBatch(ctx context.Context, subscriberID uint, targetIDs []uint) (int64, error) {
+	if err := s.checkContextCancelled(ctx, "UnsubscribeBatch"); err != nil {
+		return 0, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(targetIDs) == 0 {
+		s.logger.WarnContext(ctx, "no targets to unsubscribe from")
+		return 0, InvalidArgumentError("Target IDs must not be empty", "target_ids", "must contain at least one ID")
+	}
+
+	removedIDs, err := s.repo.UnsubscribeBatch(ctx, subscriberID, targetIDs)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete subscriptions", slog.Any("error", err))
+		return 0, status.Errorf(codes.Internal, "Failed to delete subscriptions: %v", err)
+	}
+
+	for _, removedID := range removedIDs {
+		s.publishEvent(ctx, events.SubscriptionDeleted, subscriberID, removedID)
+	}
+
+	s.logger.InfoContext(ctx, "subscriptions deleted successfully")
+	return int64(len(removedIDs)), nil
+}
+
+// MergeUser переносит подписки объединяемого аккаунта fromID на сохраняемый аккаунт toID
+func (s *subscriptionService) MergeUser(ctx context.Context, fromID uint, toID uint) (repository.MergeResult, error) {
+	if err := s.checkContextCancelled(ctx, "MergeUser"); err != nil {
+		return repository.MergeResult{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	if fromID == toID {
+		s.logger.WarnContext(ctx, "cannot merge user into itself")
+		return repository.MergeResult{}, InvalidArgumentError("Cannot merge user into itself", "to_id", "must differ from from_id")
+	}
+
+	result, err := s.repo.MergeUser(ctx, fromID, toID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to merge user", slog.Any("error", err))
+		return repository.MergeResult{}, status.Errorf(codes.Internal, "Failed to merge user: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "user merged successfully")
+	return result, nil
+}
+
+// GetFollowersForMigration выгружает подписчиков автора, переходящего на новый аккаунт,
+// пачками не больше MaxPageSize, чтобы предложить им подписаться на новый аккаунт
+func (s *subscriptionService) GetFollowersForMigration(ctx context.Context, oldUserID uint, handle func([]uint) error) error {
+	if err := s.checkContextCancelled(ctx, "GetFollowersForMigration"); err != nil {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	if err := s.repo.GetFollowersForMigration(ctx, oldUserID, s.options.MaxPageSize, handle); err != nil {
+		s.logger.ErrorContext(ctx, "failed to stream followers for migration", slog.Any("error", err))
+		if errors.Is(err, context.Canceled) {
+			return status.Error(codes.Canceled, err.Error())
+		}
+		return status.Errorf(codes.Internal, "Failed to stream followers for migration: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "followers for migration streamed successfully")
+	return nil
+}
+
+// BulkSubscribeFollowersTo подписывает на новый аккаунт newUserID подписчиков старого аккаунта oldUserID,
+// давших согласие (followerIDs). Пользователи, не подписанные на oldUserID, пропускаются.
+// Подписки создаются пачками не больше MaxPageSize; повторный вызов не создает дубликатов.
+// Возвращает число созданных подписок
+func (s *subscriptionService) BulkSubscribeFollowersTo(ctx context.Context, oldUserID uint, newUserID uint, followerIDs []uint) (int64, error) {
+	if err := s.checkContextCancelled(ctx, "BulkSubscribeFollowersTo"); err != nil {
+		return 0, status.Error(codes.Canceled, err.Error())
+	}
+
+	if oldUserID == newUserID {
+		s.logger.WarnContext(ctx, "cannot migrate followers to the same account")
+		return 0, InvalidArgumentError("Cannot migrate followers to the same account", "new_user_id", "must differ from old_user_id")
+	}
+	if len(followerIDs) == 0 {
+		s.logger.WarnContext(ctx, "no followers to migrate")
+		return 0, InvalidArgumentError("Follower IDs must not be empty", "follower_ids", "must contain at least one ID")
+	}
+
+	subscribedIDs, err := s.repo.BulkSubscribeFollowersTo(ctx, oldUserID, newUserID, followerIDs, s.options.MaxPageSize)
+	// События публикуются и для пачек, записанных до ошибки
+	for _, subscribedID := range subscribedIDs {
+		s.publishEvent(ctx, events.SubscriptionCreated, subscribedID, newUserID)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to migrate followers", slog.Any("error", err), slog.Int("subscribed", len(subscribedIDs)))
+		if errors.Is(err, context.Canceled) {
+			return int64(len(subscribedIDs)), status.Error(codes.Canceled, err.Error())
+		}
+		return int64(len(subscribedIDs)), status.Errorf(codes.Internal, "Failed to migrate followers: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "followers migrated successfully", slog.Int("subscribed", len(subscribedIDs)))
+	return int64(len(subscribedIDs)), nil
+}
+
+// FindDuplicateSubscriptions возвращает пары пользователей с несколькими действующими подписками
+func (s *subscriptionService) FindDuplicateSubscriptions(ctx context.Context) ([]repository.DuplicateSubscription, error) {
+	if err := s.checkContextCancelled(ctx, "FindDuplicateSubscriptions"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	duplicates, err := s.repo.FindDuplicateSubscriptions(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find duplicate subscriptions", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to find duplicate subscriptions: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "duplicate subscriptions fetched successfully")
+	return duplicates, nil
+}
+
+// DedupeSubscriptions удаляет дубликаты подписок, оставляя самую раннюю подписку каждой пары, и возвращает число удаленных строк
+func (s *subscriptionService) DedupeSubscriptions(ctx context.Context) (int64, error) {
+	if err := s.checkContextCancelled(ctx, "DedupeSubscriptions"); err != nil {
+		return 0, status.Error(codes.Canceled, err.Error())
+	}
+
+	removed, err := s.repo.DedupeSubscriptions(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to dedupe subscriptions", slog.Any("error", err))
+		return 0, status.Errorf(codes.Internal, "Failed to dedupe subscriptions: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscriptions deduplicated successfully")
+	return removed, nil
+}
+
 // GetSubscriptions получает список подписок пользователя
 func (s *subscriptionService) GetSubscriptions(ctx context.Context, userID uint) ([]uint, error) {
 	if err := s.checkContextCancelled(ctx, "GetSubscriptions"); err != nil {
@@ -137,6 +467,220 @@ func (s *subscriptionService) GetSubscribers(ctx context.Context, userID uint) (
 	return subscriberIDs, nil
 }
 
+// CountSubscribersSince считает новых подписчиков пользователя после указанного момента.
+// Нулевой момент означает подсчет всех подписчиков
+func (s *subscriptionService) CountSubscribersSince(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	if err := s.checkContextCancelled(ctx, "CountSubscribersSince"); err != nil {
+		return 0, status.Error(codes.Canceled, err.Error())
+	}
+
+	count, err := s.repo.CountSubscribersSince(ctx, userID, since)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count subscribers", slog.Any("error", err))
+		return 0, status.Errorf(codes.Internal, "Failed to count subscribers: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscribers counted successfully")
+	return count, nil
+}
+
+// GetProfileConnections получает страницу подписок и страницу подписчиков пользователя с их общим числом одним вызовом.
+// Обе страницы имеют одинаковые размер и смещение
+func (s *subscriptionService) GetProfileConnections(ctx context.Context, userID uint, page repository.Page) (repository.ProfileConnections, error) {
+	if err := s.checkContextCancelled(ctx, "GetProfileConnections"); err != nil {
+		return repository.ProfileConnections{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	connections, err := s.repo.GetProfileConnections(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get profile connections", slog.Any("error", err))
+		return repository.ProfileConnections{}, status.Errorf(codes.Internal, "Failed to get profile connections: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "profile connections fetched successfully")
+	return connections, nil
+}
+
+// maxGrowthBuckets ограничивает число интервалов в одном запросе статистики прироста подписчиков
+const maxGrowthBuckets = 366
+
+// GetSubscriptionGrowth возвращает число новых подписчиков пользователя в [from, to) по суткам или неделям.
+// Диапазон должен быть непустым и содержать не больше maxGrowthBuckets интервалов
+func (s *subscriptionService) GetSubscriptionGrowth(ctx context.Context, userID uint, from time.Time, to time.Time, bucket repository.GrowthBucket) ([]repository.GrowthPoint, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscriptionGrowth"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if _, err := repository.ParseGrowthBucket(string(bucket)); err != nil {
+		return nil, InvalidArgumentError("Invalid growth bucket", "bucket", "must be day or week")
+	}
+	if !from.Before(to) {
+		return nil, InvalidArgumentError("Invalid growth range", "to", "must be after from")
+	}
+	if to.Sub(from) > maxGrowthBuckets*bucket.Duration() {
+		return nil, InvalidArgumentError("Growth range is too long", "to", fmt.Sprintf("must cover at most %d buckets", maxGrowthBuckets))
+	}
+
+	points, err := s.repo.GetSubscriptionGrowth(ctx, userID, from, to, bucket)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscription growth", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get subscription growth: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscription growth fetched successfully")
+	return points, nil
+}
+
+// GetSubscribersSnapshot получает страницу подписчиков пользователя по курсору снимка. В отличие от постраничной
+// выборки по смещению, при обходе всех страниц каждый подписчик на момент первой страницы возвращается ровно один раз,
+// даже если список меняется между запросами. Предназначен для выгрузки полного списка подписчиков
+func (s *subscriptionService) GetSubscribersSnapshot(ctx context.Context, userID uint, cursor repository.SnapshotCursor, limit int) (repository.SnapshotPage, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscribersSnapshot"); err != nil {
+		return repository.SnapshotPage{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	if cursor.MaxID != 0 && cursor.SnapshotAt.IsZero() {
+		return repository.SnapshotPage{}, InvalidArgumentError("Invalid snapshot cursor", "cursor", "snapshot time must be set together with max ID")
+	}
+
+	page := s.normalizePage(repository.Page{Limit: limit})
+	snapshot, err := s.repo.GetSubscribersSnapshot(ctx, userID, cursor, page.Limit)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscribers snapshot", slog.Any("error", err))
+		return repository.SnapshotPage{}, status.Errorf(codes.Internal, "Failed to get subscribers snapshot: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscribers snapshot fetched successfully")
+	return snapshot, nil
+}
+
+// GetSubscribersWithUsernames получает страницу подписчиков пользователя вместе с их именами
+func (s *subscriptionService) GetSubscribersWithUsernames(ctx context.Context, userID uint, page repository.Page) ([]repository.UserSummary, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscribersWithUsernames"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	subscribers, err := s.repo.GetSubscribersWithUsernames(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscribers with usernames", slog.Any("error", err))
+		return nil, feedError(fmt.Sprintf("Failed to get subscribers with usernames: %v", err), err)
+	}
+
+	s.logger.InfoContext(ctx, "subscribers with usernames fetched successfully")
+	return subscribers, nil
+}
+
+// GetMutualSubscribers получает страницу взаимных подписчиков пользователя и их общее число.
+// При withUsernames для пользователей страницы дополнительно разрешаются имена
+func (s *subscriptionService) GetMutualSubscribers(ctx context.Context, userID uint, page repository.Page, withUsernames bool) (repository.MutualSubscribers, error) {
+	if err := s.checkContextCancelled(ctx, "GetMutualSubscribers"); err != nil {
+		return repository.MutualSubscribers{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	mutual, err := s.repo.GetMutualSubscribers(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get mutual subscribers", slog.Any("error", err))
+		return repository.MutualSubscribers{}, status.Errorf(codes.Internal, "Failed to get mutual subscribers: %v", err)
+	}
+
+	if withUsernames {
+		mutual.Users, err = s.repo.ResolveUsernames(ctx, mutual.UserIDs)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to resolve mutual subscriber usernames", slog.Any("error", err))
+			return repository.MutualSubscribers{}, feedError(fmt.Sprintf("Failed to resolve usernames: %v", err), err)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "mutual subscribers fetched successfully")
+	return mutual, nil
+}
+
+// GetNonReciprocalFollowers получает страницу подписчиков пользователя, на которых он не подписан в ответ
+func (s *subscriptionService) GetNonReciprocalFollowers(ctx context.Context, userID uint, page repository.Page) ([]uint, error) {
+	if err := s.checkContextCancelled(ctx, "GetNonReciprocalFollowers"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	followerIDs, err := s.repo.GetNonReciprocalFollowers(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get non-reciprocal followers", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get non-reciprocal followers: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "non-reciprocal followers fetched successfully")
+	return followerIDs, nil
+}
+
+// GetSubscriptionsWithMutualFlag получает страницу подписок пользователя с признаком взаимной подписки
+func (s *subscriptionService) GetSubscriptionsWithMutualFlag(ctx context.Context, userID uint, page repository.Page) ([]repository.SubscriptionWithMutual, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscriptionsWithMutualFlag"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	subscriptions, err := s.repo.GetSubscriptionsWithMutualFlag(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscriptions with mutual flag", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get subscriptions with mutual flag: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscriptions with mutual flag fetched successfully")
+	return subscriptions, nil
+}
+
+// CountCommonSubscriptions считает пользователей, на которых подписаны оба пользователя
+func (s *subscriptionService) CountCommonSubscriptions(ctx context.Context, firstUserID uint, secondUserID uint) (int64, error) {
+	if err := s.checkContextCancelled(ctx, "CountCommonSubscriptions"); err != nil {
+		return 0, status.Error(codes.Canceled, err.Error())
+	}
+
+	count, err := s.repo.CountCommonSubscriptions(ctx, firstUserID, secondUserID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count common subscriptions", slog.Any("error", err))
+		return 0, status.Errorf(codes.Internal, "Failed to count common subscriptions: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "common subscriptions counted successfully")
+	return count, nil
+}
+
+// GetFollowedByUsers получает страницу пользователей, на которых подписан хотя бы один из followerIDs,
+// с числом таких подписчиков. Размер набора ограничен максимальным размером страницы
+func (s *subscriptionService) GetFollowedByUsers(ctx context.Context, followerIDs []uint, page repository.Page) ([]repository.FollowedUser, error) {
+	if err := s.checkContextCancelled(ctx, "GetFollowedByUsers"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(followerIDs) > s.options.MaxPageSize {
+		s.logger.WarnContext(ctx, "too many follower IDs", slog.Int("count", len(followerIDs)))
+		return nil, InvalidArgumentError(fmt.Sprintf("Too many follower IDs: maximum is %d", s.options.MaxPageSize), "follower_ids", fmt.Sprintf("must contain at most %d IDs", s.options.MaxPageSize))
+	}
+
+	followed, err := s.repo.GetFollowedByUsers(ctx, followerIDs, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get users followed by set", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get users followed by set: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "users followed by set fetched successfully")
+	return followed, nil
+}
+
+// GetNonFollowingBack получает страницу пользователей, на которых подписан пользователь, но которые не подписаны на него в ответ
+func (s *subscriptionService) GetNonFollowingBack(ctx context.Context, userID uint, page repository.Page) ([]uint, error) {
+	if err := s.checkContextCancelled(ctx, "GetNonFollowingBack"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	subscribedToIDs, err := s.repo.GetNonFollowingBack(ctx, userID, s.normalizePage(page))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscriptions not following back", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get subscriptions not following back: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscriptions not following back fetched successfully")
+	return subscribedToIDs, nil
+}
+
 // IsSubscribed проверяет, подписан ли пользователь на другого пользователя
 func (s *subscriptionService) IsSubscribed(ctx context.Context, subscriberID uint, subscribeToID uint) (bool, error) {
 	if err := s.checkContextCancelled(ctx, "IsSubscribed"); err != nil {
@@ -153,16 +697,242 @@ func (s *subscriptionService) IsSubscribed(ctx context.Context, subscriberID uin
 	return isSubscribed, nil
 }
 
+// GetSubscriptionDetail проверяет, подписан ли пользователь на другого пользователя, и возвращает дату подписки
+func (s *subscriptionService) GetSubscriptionDetail(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.SubscriptionDetail, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscriptionDetail"); err != nil {
+		return repository.SubscriptionDetail{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	detail, err := s.repo.GetSubscriptionDetail(ctx, subscriberID, subscribeToID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscription detail", slog.Any("error", err))
+		return repository.SubscriptionDetail{}, status.Errorf(codes.Internal, "Failed to get subscription detail: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscription detail fetched successfully")
+	return detail, nil
+}
+
+// GetSubscriptionStatus проверяет, подписан ли пользователь на другого пользователя и является ли подписка взаимной
+func (s *subscriptionService) GetSubscriptionStatus(ctx context.Context, subscriberID uint, subscribeToID uint) (repository.SubscriptionStatus, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscriptionStatus"); err != nil {
+		return repository.SubscriptionStatus{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	subscriptionStatus, err := s.repo.GetSubscriptionStatus(ctx, subscriberID, subscribeToID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check subscription status", slog.Any("error", err))
+		return repository.SubscriptionStatus{}, status.Errorf(codes.Internal, "Failed to check subscription status: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscription status checked successfully")
+	return subscriptionStatus, nil
+}
+
+// GetProfileSummary получает для заголовка профиля число его подписок, подписчиков и взаимных подписок,
+// а также признак подписки просматривающего пользователя на профиль
+func (s *subscriptionService) GetProfileSummary(ctx context.Context, viewerID uint, profileID uint) (repository.ProfileSummary, error) {
+	if err := s.checkContextCancelled(ctx, "GetProfileSummary"); err != nil {
+		return repository.ProfileSummary{}, status.Error(codes.Canceled, err.Error())
+	}
+
+	summary, err := s.repo.GetProfileSummary(ctx, viewerID, profileID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get profile summary", slog.Any("error", err))
+		return repository.ProfileSummary{}, status.Errorf(codes.Internal, "Failed to get profile summary: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "profile summary fetched successfully")
+	return summary, nil
+}
+
+// AreConnected проверяет, связаны ли пользователи подпиской в любом направлении
+func (s *subscriptionService) AreConnected(ctx context.Context, firstUserID uint, secondUserID uint) (bool, error) {
+	if err := s.checkContextCancelled(ctx, "AreConnected"); err != nil {
+		return false, status.Error(codes.Canceled, err.Error())
+	}
+
+	connected, err := s.repo.AreConnected(ctx, firstUserID, secondUserID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check connection", slog.Any("error", err))
+		return false, status.Errorf(codes.Internal, "Failed to check connection: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "connection checked successfully")
+	return connected, nil
+}
+
+// GetRelationships получает для каждого пользователя из списка признаки взаимных подписок с просматривающим пользователем
+func (s *subscriptionService) GetRelationships(ctx context.Context, viewerID uint, targetIDs []uint) (map[uint]repository.Relationship, error) {
+	if err := s.checkContextCancelled(ctx, "GetRelationships"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(targetIDs) == 0 {
+		s.logger.WarnContext(ctx, "no targets to get relationships for")
+		return nil, InvalidArgumentError("Target IDs must not be empty", "target_ids", "must contain at least one ID")
+	}
+	if len(targetIDs) > s.options.MaxPageSize {
+		s.logger.WarnContext(ctx, "too many targets to get relationships for")
+		return nil, InvalidArgumentError(fmt.Sprintf("Too many target IDs: maximum is %d", s.options.MaxPageSize), "target_ids", fmt.Sprintf("must contain at most %d IDs", s.options.MaxPageSize))
+	}
+
+	relationships, err := s.repo.GetRelationships(ctx, viewerID, targetIDs)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get relationships", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get relationships: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "relationships fetched successfully")
+	return relationships, nil
+}
+
+// FindMissingUsers возвращает пользователей из списка, которых нет в сервисе пользователей.
+// Предназначен для проверки целей перед импортом списка подписок; размер списка ограничен MaxPageSize
+func (s *subscriptionService) FindMissingUsers(ctx context.Context, userIDs []uint) ([]uint, error) {
+	if err := s.checkContextCancelled(ctx, "FindMissingUsers"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(userIDs) == 0 {
+		s.logger.WarnContext(ctx, "no users to check")
+		return nil, InvalidArgumentError("User IDs must not be empty", "user_ids", "must contain at least one ID")
+	}
+	if len(userIDs) > s.options.MaxPageSize {
+		s.logger.WarnContext(ctx, "too many users to check")
+		return nil, InvalidArgumentError(fmt.Sprintf("Too many user IDs: maximum is %d", s.options.MaxPageSize), "user_ids", fmt.Sprintf("must contain at most %d IDs", s.options.MaxPageSize))
+	}
+
+	missingIDs, err := s.repo.FindMissingUsers(ctx, userIDs)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check users existence", slog.Any("error", err))
+		return nil, feedError(fmt.Sprintf("Failed to check users existence: %v", err), err)
+	}
+
+	s.logger.InfoContext(ctx, "users existence checked successfully")
+	return missingIDs, nil
+}
+
+// maxGroupSize ограничивает число участников группы, связи между которыми запрашиваются одним вызовом
+const maxGroupSize = 50
+
+// GetGroupRelationships возвращает подписки между участниками небольшой группы: кто в группе на кого подписан
+func (s *subscriptionService) GetGroupRelationships(ctx context.Context, userIDs []uint) ([]repository.GroupEdge, error) {
+	if err := s.checkContextCancelled(ctx, "GetGroupRelationships"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(userIDs) < 2 {
+		s.logger.WarnContext(ctx, "not enough group members to get relationships for")
+		return nil, InvalidArgumentError("Group must contain at least two users", "user_ids", "must contain at least two IDs")
+	}
+	if len(userIDs) > maxGroupSize {
+		s.logger.WarnContext(ctx, "too many group members to get relationships for")
+		return nil, InvalidArgumentError(fmt.Sprintf("Too many user IDs: maximum is %d", maxGroupSize), "user_ids", fmt.Sprintf("must contain at most %d IDs", maxGroupSize))
+	}
+
+	edges, err := s.repo.GetGroupRelationships(ctx, userIDs)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get group relationships", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get group relationships: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "group relationships fetched successfully")
+	return edges, nil
+}
+
+// GetSubscriptionHistory возвращает историю подписок пользователя на другого пользователя, включая отписки
+func (s *subscriptionService) GetSubscriptionHistory(ctx context.Context, subscriberID uint, subscribeToID uint) ([]repository.SubscriptionHistoryEntry, error) {
+	if err := s.checkContextCancelled(ctx, "GetSubscriptionHistory"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	history, err := s.repo.GetSubscriptionHistory(ctx, subscriberID, subscribeToID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get subscription history", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Failed to get subscription history: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscription history fetched successfully")
+	return history, nil
+}
+
+// StreamAllSubscriptions выгружает весь граф подписок пачками для аналитики
+func (s *subscriptionService) StreamAllSubscriptions(ctx context.Context, batchSize int, handle func([]repository.SubscriptionRecord) error) error {
+	if err := s.checkContextCancelled(ctx, "StreamAllSubscriptions"); err != nil {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	page := s.normalizePage(repository.Page{Limit: batchSize})
+	if err := s.repo.StreamAllSubscriptions(ctx, page.Limit, handle); err != nil {
+		s.logger.ErrorContext(ctx, "failed to stream subscriptions", slog.Any("error", err))
+		if errors.Is(err, context.Canceled) {
+			return status.Error(codes.Canceled, err.Error())
+		}
+		return status.Errorf(codes.Internal, "Failed to stream subscriptions: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "subscriptions streamed successfully")
+	return nil
+}
+
+// MarkFeedSeen отмечает ленту пользователя просмотренной до момента seenAt.
+// Момент в будущем заменяется текущим, чтобы не скрыть элементы, которые появятся позже
+func (s *subscriptionService) MarkFeedSeen(ctx context.Context, userID uint, seenAt time.Time) error {
+	if err := s.checkContextCancelled(ctx, "MarkFeedSeen"); err != nil {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	if seenAt.IsZero() {
+		return InvalidArgumentError("Seen time must be set", "seen_at", "must not be empty")
+	}
+	if now := time.Now(); seenAt.After(now) {
+		seenAt = now
+	}
+
+	if err := s.repo.MarkFeedSeen(ctx, userID, seenAt); err != nil {
+		s.logger.ErrorContext(ctx, "failed to mark feed seen", slog.Any("error", err))
+		return status.Errorf(codes.Internal, "Failed to mark feed seen: %v", err)
+	}
+
+	s.logger.InfoContext(ctx, "feed marked seen successfully")
+	return nil
+}
+
+// unseenFeedOptions формирует параметры построения ленты; в режиме Unseen лента ограничивается
+// элементами, появившимися после сохраненной отметки просмотра. Без отметки возвращается вся лента
+func (s *subscriptionService) unseenFeedOptions(ctx context.Context, userID uint, params FeedParams) (repository.FeedOptions, error) {
+	opts := s.feedOptions(params)
+	if !params.Unseen {
+		return opts, nil
+	}
+
+	since, err := s.repo.GetFeedWatermark(ctx, userID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get feed watermark", slog.Any("error", err))
+		return repository.FeedOptions{}, status.Errorf(codes.Internal, "Failed to get feed watermark: %v", err)
+	}
+	opts.Since = since
+	return opts, nil
+}
+
 // GetWatchlistsBySubscription получает вотчлисты пользователей, на которых подписан пользователь
-func (s *subscriptionService) GetWatchlistsBySubscription(ctx context.Context, userID uint) ([]*subscription.WatchlistItem, error) {
+func (s *subscriptionService) GetWatchlistsBySubscription(ctx context.Context, userID uint, params FeedParams) (*repository.WatchlistFeed, error) {
 	if err := s.checkContextCancelled(ctx, "GetWatchlistsBySubscription"); err != nil {
 		return nil, status.Error(codes.Canceled, err.Error())
 	}
+	if err := params.Shard.Validate(); err != nil {
+		return nil, InvalidArgumentError("Invalid feed shard", "shard", err.Error())
+	}
 
-	watchlists, err := s.repo.GetWatchlistsBySubscription(ctx, userID)
+	opts, err := s.unseenFeedOptions(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	watchlists, err := s.repo.GetWatchlistsBySubscription(ctx, userID, opts)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to get watchlists", slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get watchlists: %v", err)
+		return nil, feedError(fmt.Sprintf("Failed to get watchlists: %v", err), err)
 	}
 
 	s.logger.InfoContext(ctx, "watchlists fetched successfully")
@@ -170,17 +940,196 @@ func (s *subscriptionService) GetWatchlistsBySubscription(ctx context.Context, u
 }
 
 // GetReviewsBySubscription получает отзывы пользователей, на которых подписан пользователь
-func (s *subscriptionService) GetReviewsBySubscription(ctx context.Context, userID uint) ([]*subscription.ReviewItem, error) {
+func (s *subscriptionService) GetReviewsBySubscription(ctx context.Context, userID uint, params FeedParams) (*repository.ReviewFeed, error) {
 	if err := s.checkContextCancelled(ctx, "GetReviewsBySubscription"); err != nil {
 		return nil, status.Error(codes.Canceled, err.Error())
 	}
+	if err := params.Shard.Validate(); err != nil {
+		return nil, InvalidArgumentError("Invalid feed shard", "shard", err.Error())
+	}
+	if params.MaxContentLength < 0 {
+		return nil, InvalidArgumentError("Invalid max content length", "max_content_length", "must not be negative")
+	}
+	if params.MinRating < 0 {
+		return nil, InvalidArgumentError("Invalid min rating", "min_rating", "must not be negative")
+	}
 
-	reviews, err := s.repo.GetReviewsBySubscription(ctx, userID)
+	opts, err := s.unseenFeedOptions(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.repo.GetReviewsBySubscription(ctx, userID, opts)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to get reviews", slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get reviews: %v", err)
+		return nil, feedError(fmt.Sprintf("Failed to get reviews: %v", err), err)
+	}
+	if params.MaxContentLength > 0 {
+		reviews = truncateReviewContent(reviews, params.MaxContentLength)
 	}
 
 	s.logger.InfoContext(ctx, "reviews fetched successfully")
 	return reviews, nil
 }
+
+// GetFeedsForUsers строит ленты отзывов для нескольких пользователей с общими на весь вызов медиа и именами
+// пользователей. Предназначен для фоновых задач вроде рассылки дайджестов; число пользователей ограничено
+// максимальным размером страницы
+func (s *subscriptionService) GetFeedsForUsers(ctx context.Context, userIDs []uint) (map[uint]*repository.ReviewFeed, error) {
+	if err := s.checkContextCancelled(ctx, "GetFeedsForUsers"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if len(userIDs) > s.options.MaxPageSize {
+		s.logger.WarnContext(ctx, "too many users for batch feeds", slog.Int("count", len(userIDs)))
+		return nil, InvalidArgumentError(fmt.Sprintf("Too many user IDs: maximum is %d", s.options.MaxPageSize), "user_ids", fmt.Sprintf("must contain at most %d IDs", s.options.MaxPageSize))
+	}
+
+	feeds, err := s.repo.GetFeedsForUsers(ctx, userIDs, s.feedOptions(FeedParams{}))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to build feeds for users", slog.Any("error", err))
+		return nil, feedError(fmt.Sprintf("Failed to build feeds for users: %v", err), err)
+	}
+
+	s.logger.InfoContext(ctx, "feeds for users built successfully")
+	return feeds, nil
+}
+
+// truncateReviewContent возвращает копию ленты, в которой тексты отзывов длиннее maxLength символов обрезаны
+// по границе символа и дополнены многоточием так, чтобы вместе с ним занимать maxLength символов.
+// Элементы ленты могут быть общими с кэшем, поэтому обрезанные отзывы копируются, а не изменяются
+func truncateReviewContent(feed *repository.ReviewFeed, maxLength int) *repository.ReviewFeed {
+	truncated := *feed
+	truncated.Items = make([]*subscription.ReviewItem, len(feed.Items))
+	for i, item := range feed.Items {
+		if utf8.RuneCountInString(item.Content) <= maxLength {
+			truncated.Items[i] = item
+			continue
+		}
+		runes := []rune(item.Content)
+		copied := proto.Clone(item).(*subscription.ReviewItem)
+		copied.Content = string(runes[:maxLength-1]) + "…"
+		truncated.Items[i] = copied
+	}
+	return &truncated
+}
+
+// GetActiveSubscriptions возвращает пользователей из подписок, упорядоченных по времени последней активности.
+// Размер результата ограничивается так же, как размер страницы
+func (s *subscriptionService) GetActiveSubscriptions(ctx context.Context, userID uint, limit int) (*repository.ActiveSubscriptions, error) {
+	if err := s.checkContextCancelled(ctx, "GetActiveSubscriptions"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	page := s.normalizePage(repository.Page{Limit: limit})
+	active, err := s.repo.GetActiveSubscriptions(ctx, userID, page.Limit, s.feedOptions(FeedParams{}))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get active subscriptions", slog.Any("error", err))
+		return nil, feedError(fmt.Sprintf("Failed to get active subscriptions: %v", err), err)
+	}
+
+	s.logger.InfoContext(ctx, "active subscriptions fetched successfully")
+	return active, nil
+}
+
+// GetInactiveSubscriptions возвращает пользователей из подписок без отзывов и элементов вотчлиста начиная с inactiveSince.
+// Момент inactiveSince должен быть задан и не может быть в будущем
+func (s *subscriptionService) GetInactiveSubscriptions(ctx context.Context, userID uint, inactiveSince time.Time) (*repository.InactiveSubscriptions, error) {
+	if err := s.checkContextCancelled(ctx, "GetInactiveSubscriptions"); err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	if inactiveSince.IsZero() {
+		return nil, InvalidArgumentError("Invalid inactivity date", "inactive_since", "must be set")
+	}
+	if inactiveSince.After(time.Now()) {
+		return nil, InvalidArgumentError("Invalid inactivity date", "inactive_since", "must not be in the future")
+	}
+
+	inactive, err := s.repo.GetInactiveSubscriptions(ctx, userID, inactiveSince, s.feedOptions(FeedParams{}))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get inactive subscriptions", slog.Any("error", err))
+		return nil, feedError(fmt.Sprintf("Failed to get inactive subscriptions: %v", err), err)
+	}
+
+	s.logger.InfoContext(ctx, "inactive subscriptions fetched successfully")
+	return inactive, nil
+}
+
+// WatchSubscriptionChanges передает в handle события подписки и отписки пользователя userID, пока не завершен ctx.
+// Наблюдаются изменения, сделанные этим экземпляром сервиса. Если handle не успевает обрабатывать события
+// и буфер наблюдателя переполняется, возвращается Aborted: клиенту нужно заново получить список подписок
+func (s *subscriptionService) WatchSubscriptionChanges(ctx context.Context, userID uint, handle func(events.SubscriptionEvent) error) error {
+	watcher := s.changes.Watch(userID)
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.InfoContext(ctx, "subscription changes watch finished", slog.Any("reason", ctx.Err()))
+			return status.Error(codes.Canceled, ctx.Err().Error())
+		case event, ok := <-watcher.Events():
+			if !ok {
+				s.logger.WarnContext(ctx, "subscription changes watcher fell behind")
+				return status.Errorf(codes.Aborted, "Watcher fell behind, subscriptions must be refetched")
+			}
+			if err := handle(event); err != nil {
+				s.logger.ErrorContext(ctx, "failed to deliver subscription change", slog.Any("error", err))
+				return err
+			}
+		}
+	}
+}
+
+// downstreamRetryDelay — рекомендуемая клиенту задержка повтора после таймаута или недоступности внешнего сервиса
+const downstreamRetryDelay = time.Second
+
+// feedError преобразует ошибку построения ленты в gRPC-статус. Для ошибок внешних сервисов
+// в статус добавляется ErrorInfo с именем сервиса и метода. Таймаут и недоступность сервиса возвращаются
+// как Unavailable с RetryInfo, чтобы клиент мог повторить запрос
+func feedError(message string, err error) error {
+	var downstreamErr *repository.DownstreamError
+	if !errors.As(err, &downstreamErr) {
+		return status.Error(codes.Internal, message)
+	}
+
+	code := codes.Internal
+	if isTransientDownstreamError(downstreamErr.Err) {
+		code = codes.Unavailable
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: "DOWNSTREAM_FAILURE",
+		Domain: "subscription",
+		Metadata: map[string]string{
+			"dependency": downstreamErr.Service,
+			"method":     downstreamErr.Method,
+		},
+	}}
+	if code == codes.Unavailable {
+		retryDelay := downstreamRetryDelay
+		// Пока действует пауза вызовов сервиса, повтор раньше ее окончания снова завершится ошибкой
+		if downstreamErr.RetryAfter > retryDelay {
+			retryDelay = downstreamErr.RetryAfter
+		}
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	}
+
+	st, detailsErr := status.New(code, message).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// isTransientDownstreamError определяет, вызвана ли ошибка внешнего сервиса таймаутом или его недоступностью
+func isTransientDownstreamError(err error) bool {
+	if errors.Is(err, repository.ErrDependencyUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/watchlist-kata/subscription/internal/repository"
+	"github.com/watchlist-kata/subscription/internal/service"
+)
+
+// fakeRepository хранит подписки в памяти; методы, не нужные тестам, не реализованы
+type fakeRepository struct {
+	repository.SubscriptionRepository
+	mu            sync.Mutex
+	subscriptions map[[2]uint]bool
+	unsubscribes  int
+}
+
+// newFakeRepository создает пустой fakeRepository
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{subscriptions: make(map[[2]uint]bool)}
+}
+
+func (r *fakeRepository) UserExists(context.Context, uint) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeRepository) IsSubscribed(_ context.Context, subscriberID uint, userID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.subscriptions[[2]uint{subscriberID, userID}], nil
+}
+
+func (r *fakeRepository) Subscribe(_ context.Context, subscriberID uint, userID uint) (repository.CreatedSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[[2]uint{subscriberID, userID}] = true
+	return repository.CreatedSubscription{ID: uint(len(r.subscriptions)), CreatedAt: time.Now()}, nil
+}
+
+func (r *fakeRepository) Unsubscribe(_ context.Context, subscriberID uint, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscriptions, [2]uint{subscriberID, userID})
+	r.unsubscribes++
+	return nil
+}
+
+// newService создает сервис поверх repo с параметрами options
+func newService(repo repository.SubscriptionRepository, options service.Options) service.SubscriptionService {
+	return service.NewSubscriptionService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, options)
+}
+
+func TestSubscribeSelfSubscribePolicy(t *testing.T) {
+	tests := []struct {
+		policy         service.SelfSubscribePolicy
+		wantCode       codes.Code
+		wantSubscribed bool
+	}{
+		{policy: service.SelfSubscribeReject, wantCode: codes.InvalidArgument},
+		{policy: "", wantCode: codes.InvalidArgument},
+		{policy: service.SelfSubscribeIgnore, wantCode: codes.OK},
+		{policy: service.SelfSubscribeAllow, wantCode: codes.OK, wantSubscribed: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			repo := newFakeRepository()
+			s := newService(repo, service.Options{SelfSubscribePolicy: tt.policy})
+
+			_, err := s.Subscribe(context.Background(), 1, 1)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("got code %s (%v), want %s", got, err, tt.wantCode)
+			}
+			if subscribed, _ := repo.IsSubscribed(context.Background(), 1, 1); subscribed != tt.wantSubscribed {
+				t.Errorf("self-subscription stored: %t, want %t", subscribed, tt.wantSubscribed)
+			}
+		})
+	}
+}
+
+func TestSubscribeToOtherUserIgnoresSelfSubscribePolicy(t *testing.T) {
+	repo := newFakeRepository()
+	s := newService(repo, service.Options{SelfSubscribePolicy: service.SelfSubscribeReject})
+
+	if _, err := s.Subscribe(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if subscribed, _ := repo.IsSubscribed(context.Background(), 1, 2); !subscribed {
+		t.Error("subscription was not stored")
+	}
+}
+
+func TestUnsubscribeNotSubscribed(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		wantCode   codes.Code
+	}{
+		{name: "strict", idempotent: false, wantCode: codes.NotFound},
+		{name: "idempotent", idempotent: true, wantCode: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			s := newService(repo, service.Options{IdempotentUnsubscribe: tt.idempotent})
+
+			err := s.Unsubscribe(context.Background(), 1, 2)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("got code %s (%v), want %s", got, err, tt.wantCode)
+			}
+			if repo.unsubscribes != 0 {
+				t.Errorf("repository Unsubscribe called %d times, want none", repo.unsubscribes)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeExistingSubscription(t *testing.T) {
+	for _, idempotent := range []bool{false, true} {
+		repo := newFakeRepository()
+		repo.subscriptions[[2]uint{1, 2}] = true
+		s := newService(repo, service.Options{IdempotentUnsubscribe: idempotent})
+
+		if err := s.Unsubscribe(context.Background(), 1, 2); err != nil {
+			t.Fatalf("Unsubscribe with idempotent=%t: %v", idempotent, err)
+		}
+		if subscribed, _ := repo.IsSubscribed(context.Background(), 1, 2); subscribed {
+			t.Errorf("subscription still exists with idempotent=%t", idempotent)
+		}
+	}
+}
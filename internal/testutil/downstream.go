@@ -0,0 +1,233 @@
+// Package testutil содержит вспомогательные средства для интеграционных тестов
+package testutil
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/watchlist-kata/protos/media"
+	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/protos/user"
+	"github.com/watchlist-kata/protos/watchlist"
+)
+
+// bufferSize — размер буфера in-process соединения
+const bufferSize = 1024 * 1024
+
+// Downstream запускает in-memory реализации сервисов медиа, отзывов, вотчлистов и пользователей
+// на bufconn-листенере и предоставляет подключение к ним
+type Downstream struct {
+	Media     *FakeMediaService
+	Review    *FakeReviewService
+	Watchlist *FakeWatchlistService
+	User      *FakeUserService
+	Conn      *grpc.ClientConn
+
+	server   *grpc.Server
+	listener *bufconn.Listener
+}
+
+// StartDownstream запускает фейковые внешние сервисы и подключается к ним
+func StartDownstream() (*Downstream, error) {
+	listener := bufconn.Listen(bufferSize)
+	server := grpc.NewServer()
+
+	d := &Downstream{
+		Media:     NewFakeMediaService(),
+		Review:    NewFakeReviewService(),
+		Watchlist: NewFakeWatchlistService(),
+		User:      NewFakeUserService(),
+		server:    server,
+		listener:  listener,
+	}
+	media.RegisterMediaServiceServer(server, d.Media)
+	review.RegisterReviewServiceServer(server, d.Review)
+	watchlist.RegisterWatchlistServiceServer(server, d.Watchlist)
+	user.RegisterUserServiceServer(server, d.User)
+
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+	d.Conn = conn
+
+	return d, nil
+}
+
+// Close закрывает подключение и останавливает фейковые сервисы
+func (d *Downstream) Close() {
+	d.Conn.Close()
+	d.server.Stop()
+	d.listener.Close()
+}
+
+// callCounter считает вызовы методов фейкового сервиса
+type callCounter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// record учитывает вызов метода
+func (c *callCounter) record(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[method]++
+}
+
+// Calls возвращает число вызовов метода
+func (c *callCounter) Calls(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[method]
+}
+
+// FakeMediaService реализует media.MediaServiceServer поверх словаря медиа
+type FakeMediaService struct {
+	media.UnimplementedMediaServiceServer
+	callCounter
+	mu     sync.Mutex
+	medias map[int64]*media.Media
+}
+
+// NewFakeMediaService создает новый экземпляр FakeMediaService
+func NewFakeMediaService() *FakeMediaService {
+	return &FakeMediaService{medias: make(map[int64]*media.Media)}
+}
+
+// Add добавляет медиа
+func (f *FakeMediaService) Add(m *media.Media) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.medias[m.Id] = m
+}
+
+// GetMediaByID возвращает медиа по ID или NotFound
+func (f *FakeMediaService) GetMediaByID(ctx context.Context, req *media.GetMediaByIDRequest) (*media.Media, error) {
+	f.record("GetMediaByID")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.medias[req.Id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "media %d not found", req.Id)
+	}
+	return m, nil
+}
+
+// FakeReviewService реализует review.ReviewServiceServer поверх списка отзывов
+type FakeReviewService struct {
+	review.UnimplementedReviewServiceServer
+	callCounter
+	mu      sync.Mutex
+	reviews []*review.Review
+}
+
+// NewFakeReviewService создает новый экземпляр FakeReviewService
+func NewFakeReviewService() *FakeReviewService {
+	return &FakeReviewService{}
+}
+
+// Add добавляет отзыв
+func (f *FakeReviewService) Add(r *review.Review) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reviews = append(f.reviews, r)
+}
+
+// GetByUser возвращает отзывы пользователя
+func (f *FakeReviewService) GetByUser(ctx context.Context, req *review.GetByUserRequest) (*review.GetByUserResponse, error) {
+	f.record("GetByUser")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	response := &review.GetByUserResponse{}
+	for _, r := range f.reviews {
+		if r.UserId == req.UserId {
+			response.Reviews = append(response.Reviews, r)
+		}
+	}
+	return response, nil
+}
+
+// FakeWatchlistService реализует watchlist.WatchlistServiceServer поверх списка элементов вотчлистов
+type FakeWatchlistService struct {
+	watchlist.UnimplementedWatchlistServiceServer
+	callCounter
+	mu    sync.Mutex
+	items []*watchlist.WatchlistItem
+}
+
+// NewFakeWatchlistService создает новый экземпляр FakeWatchlistService
+func NewFakeWatchlistService() *FakeWatchlistService {
+	return &FakeWatchlistService{}
+}
+
+// Add добавляет элемент вотчлиста
+func (f *FakeWatchlistService) Add(item *watchlist.WatchlistItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = append(f.items, item)
+}
+
+// GetWatchlist возвращает вотчлист пользователя
+func (f *FakeWatchlistService) GetWatchlist(ctx context.Context, req *watchlist.GetWatchlistRequest) (*watchlist.GetWatchlistResponse, error) {
+	f.record("GetWatchlist")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	response := &watchlist.GetWatchlistResponse{}
+	for _, item := range f.items {
+		if item.UserId == req.UserId {
+			response.Watchlists = append(response.Watchlists, item)
+		}
+	}
+	return response, nil
+}
+
+// FakeUserService реализует user.UserServiceServer поверх словаря пользователей
+type FakeUserService struct {
+	user.UnimplementedUserServiceServer
+	callCounter
+	mu    sync.Mutex
+	users map[int64]*user.User
+}
+
+// NewFakeUserService создает новый экземпляр FakeUserService
+func NewFakeUserService() *FakeUserService {
+	return &FakeUserService{users: make(map[int64]*user.User)}
+}
+
+// Add добавляет пользователя
+func (f *FakeUserService) Add(u *user.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[u.Id] = u
+}
+
+// GetByID возвращает пользователя по ID или NotFound
+func (f *FakeUserService) GetByID(ctx context.Context, req *user.GetUserRequest) (*user.GetUserResponse, error) {
+	f.record("GetByID")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[req.Id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "user %d not found", req.Id)
+	}
+	return &user.GetUserResponse{User: u}, nil
+}
@@ -0,0 +1,152 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// subscriptionsQuery — начало запроса GetSubscriptions, который понимает SubscriptionDB
+const subscriptionsQuery = `SELECT * FROM "subscription" WHERE subscriber_id = $1`
+
+// SubscriptionDB — in-memory хранилище подписок за драйвером database/sql.
+// Поддерживает только выборку подписок пользователя, которой лента получает список подписок;
+// остальные запросы завершаются ошибкой, чтобы тест не получил молча пустой результат
+type SubscriptionDB struct {
+	mu            sync.Mutex
+	subscriptions map[uint][]uint
+	queries       int
+}
+
+// NewSubscriptionDB создает пустое хранилище подписок
+func NewSubscriptionDB() *SubscriptionDB {
+	return &SubscriptionDB{subscriptions: make(map[uint][]uint)}
+}
+
+// Subscribe добавляет подписки subscriberID на пользователей userIDs
+func (s *SubscriptionDB) Subscribe(subscriberID uint, userIDs ...uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[subscriberID] = append(s.subscriptions[subscriberID], userIDs...)
+}
+
+// Queries возвращает число выполненных запросов
+func (s *SubscriptionDB) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// Gorm возвращает подключение GORM с диалектом Postgres поверх хранилища
+func (s *SubscriptionDB) Gorm() (*gorm.DB, error) {
+	return gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(subscriptionConnector{db: s})}), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+}
+
+// query возвращает подписки пользователя, упорядоченные по ID, как это делает Postgres для GetSubscriptions
+func (s *SubscriptionDB) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, subscriptionsQuery) || len(args) != 1 {
+		return nil, fmt.Errorf("testutil: unsupported query %q", query)
+	}
+	subscriberID, ok := args[0].Value.(int64)
+	if !ok {
+		return nil, fmt.Errorf("testutil: unexpected subscriber_id %v", args[0].Value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	userIDs := slices.Clone(s.subscriptions[uint(subscriberID)])
+	slices.Sort(userIDs)
+
+	rows := &subscriptionRows{}
+	now := time.Now()
+	for i, userID := range userIDs {
+		rows.values = append(rows.values, []driver.Value{int64(i + 1), subscriberID, int64(userID), now, now, nil})
+	}
+	return rows, nil
+}
+
+// subscriptionConnector открывает подключения к SubscriptionDB
+type subscriptionConnector struct {
+	db *SubscriptionDB
+}
+
+// Connect возвращает новое подключение к хранилищу
+func (c subscriptionConnector) Connect(context.Context) (driver.Conn, error) {
+	return &subscriptionConn{db: c.db}, nil
+}
+
+// Driver возвращает драйвер подключений
+func (c subscriptionConnector) Driver() driver.Driver {
+	return subscriptionDriver{}
+}
+
+// subscriptionDriver — драйвер database/sql, подключения которого создаются только через subscriptionConnector
+type subscriptionDriver struct{}
+
+// Open не поддерживается: хранилище не адресуется строкой подключения
+func (subscriptionDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("testutil: use SubscriptionDB.Gorm")
+}
+
+// subscriptionConn — подключение к SubscriptionDB; запросы выполняются без подготовки
+type subscriptionConn struct {
+	db *SubscriptionDB
+}
+
+// Prepare не поддерживается: запросы выполняются через QueryContext
+func (c *subscriptionConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("testutil: prepared statements are not supported")
+}
+
+// Close закрывает подключение
+func (c *subscriptionConn) Close() error {
+	return nil
+}
+
+// Begin не поддерживается: хранилище только читается
+func (c *subscriptionConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("testutil: transactions are not supported")
+}
+
+// QueryContext выполняет запрос к хранилищу
+func (c *subscriptionConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, args)
+}
+
+// subscriptionRows — строки таблицы subscription, возвращаемые запросом
+type subscriptionRows struct {
+	values [][]driver.Value
+}
+
+// Columns возвращает столбцы таблицы subscription
+func (r *subscriptionRows) Columns() []string {
+	return []string{"id", "subscriber_id", "user_id", "created_at", "updated_at", "deleted_at"}
+}
+
+// Close закрывает строки
+func (r *subscriptionRows) Close() error {
+	return nil
+}
+
+// Next заполняет dest значениями следующей строки
+func (r *subscriptionRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -30,6 +31,7 @@ type KafkaHandler struct {
 	wg        sync.WaitGroup
 	quitChan  chan struct{}
 	saramaCfg *sarama.Config
+	dropped   atomic.Uint64
 }
 
 // NewKafkaHandler initializes a new KafkaHandler.
@@ -64,33 +66,43 @@ func NewKafkaHandler(brokers []string, topic string, bufferSize int) (*KafkaHand
 }
 
 // processLogs sends logs into channel for asynchronous processing.
+// On shutdown the records left in the buffer are sent before returning.
 func (k *KafkaHandler) processLogs() {
 	defer k.wg.Done()
 	for {
 		select {
 		case record := <-k.logChan:
-			logEntry := map[string]interface{}{
-				"time":  record.Time.Format(time.RFC3339),
-				"level": record.Level.String(),
-				"msg":   record.Message,
-			}
-			payload, err := json.Marshal(logEntry)
-			if err != nil {
-				fmt.Printf("failed to marshal log entry: %v\n", err)
-				continue
-			}
-
-			message := &sarama.ProducerMessage{
-				Topic: k.topic,
-				Key:   sarama.StringEncoder("log"),
-				Value: sarama.ByteEncoder(payload),
+			k.send(record)
+		case <-k.quitChan:
+			for {
+				select {
+				case record := <-k.logChan:
+					k.send(record)
+				default:
+					return
+				}
 			}
+		}
+	}
+}
 
-			k.producer.Input() <- message
+// send passes a log record to the producer. It blocks while the producer input is full.
+func (k *KafkaHandler) send(record slog.Record) {
+	logEntry := map[string]interface{}{
+		"time":  record.Time.Format(time.RFC3339),
+		"level": record.Level.String(),
+		"msg":   record.Message,
+	}
+	payload, err := json.Marshal(logEntry)
+	if err != nil {
+		fmt.Printf("failed to marshal log entry: %v\n", err)
+		return
+	}
 
-		case <-k.quitChan:
-			return
-		}
+	k.producer.Input() <- &sarama.ProducerMessage{
+		Topic: k.topic,
+		Key:   sarama.StringEncoder("log"),
+		Value: sarama.ByteEncoder(payload),
 	}
 }
 
@@ -121,11 +133,32 @@ func (k *KafkaHandler) Handle(ctx context.Context, record slog.Record) error {
 	case k.logChan <- record:
 		return nil
 	default:
+		k.dropped.Add(1)
 		fmt.Println("log channel is full, dropping log message")
 		return nil
 	}
 }
 
+// Sink returns the name of the sink for buffer statistics.
+func (k *KafkaHandler) Sink() string {
+	return "kafka"
+}
+
+// BufferSize returns the capacity of the log buffer.
+func (k *KafkaHandler) BufferSize() int {
+	return cap(k.logChan)
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (k *KafkaHandler) Dropped() uint64 {
+	return k.dropped.Load()
+}
+
+// Pending returns the number of records waiting in the buffer.
+func (k *KafkaHandler) Pending() int {
+	return len(k.logChan)
+}
+
 // WithAttrs adds attributes to the handler.
 func (k *KafkaHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return k
@@ -152,6 +185,7 @@ type FileHandler struct {
 	logChan  chan slog.Record
 	wg       sync.WaitGroup
 	quitChan chan struct{}
+	dropped  atomic.Uint64
 }
 
 // NewFileHandler initializes a new FileHandler.
@@ -181,19 +215,32 @@ func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
 }
 
 // processLogs reads log records from a channel and writes them to the file.
+// On shutdown the records left in the buffer are written before returning.
 func (f *FileHandler) processLogs() {
 	defer f.wg.Done()
 	for {
 		select {
 		case record := <-f.logChan:
-			line := fmt.Sprintf("[%s] - %s - %s", record.Level.String(), record.Time.Format(time.RFC3339), record.Message)
-			f.file.Write(append([]byte(line), '\n'))
+			f.write(record)
 		case <-f.quitChan:
-			return
+			for {
+				select {
+				case record := <-f.logChan:
+					f.write(record)
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
+// write appends a log record to the file.
+func (f *FileHandler) write(record slog.Record) {
+	line := fmt.Sprintf("[%s] - %s - %s", record.Level.String(), record.Time.Format(time.RFC3339), record.Message)
+	f.file.Write(append([]byte(line), '\n'))
+}
+
 // Enabled checks if the level is enabled.
 func (f *FileHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return true
@@ -205,11 +252,32 @@ func (f *FileHandler) Handle(ctx context.Context, record slog.Record) error {
 	case f.logChan <- record:
 		return nil
 	default:
+		f.dropped.Add(1)
 		fmt.Println("file log channel is full, dropping log message")
 		return nil
 	}
 }
 
+// Sink returns the name of the sink for buffer statistics.
+func (f *FileHandler) Sink() string {
+	return "file"
+}
+
+// BufferSize returns the capacity of the log buffer.
+func (f *FileHandler) BufferSize() int {
+	return cap(f.logChan)
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (f *FileHandler) Dropped() uint64 {
+	return f.dropped.Load()
+}
+
+// Pending returns the number of records waiting in the buffer.
+func (f *FileHandler) Pending() int {
+	return len(f.logChan)
+}
+
 // WithAttrs adds attributes to the handler.
 func (f *FileHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return f
@@ -336,17 +404,109 @@ func (m *MultiHandler) WithGroup(name string) slog.Handler {
 	return NewMultiHandler(handlers...)
 }
 
-// CloseAll closes all handlers that implement the Close method.
-func (m *MultiHandler) CloseAll() {
+// CloseAll closes all handlers that implement the Close method, waiting at most timeout
+// for them to flush buffered records. A handler blocked on its backend (for example,
+// an unreachable Kafka broker) is abandoned at the deadline, and a warning with the
+// number of records still in its buffer is printed.
+func (m *MultiHandler) CloseAll(timeout time.Duration) {
+	var wg sync.WaitGroup
 	for _, h := range m.handlers {
 		if closer, ok := h.(interface{ Close() error }); ok {
-			closer.Close()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := closer.Close(); err != nil {
+					fmt.Printf("failed to close log handler: %v\n", err)
+				}
+			}()
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		for _, h := range m.handlers {
+			if buffered, ok := h.(bufferedHandler); ok && buffered.Pending() > 0 {
+				fmt.Printf("WARNING: %s log handler did not flush within %s, dropping %d buffered log records\n",
+					buffered.Sink(), timeout, buffered.Pending())
+			}
 		}
+		fmt.Printf("WARNING: log handlers did not close within %s\n", timeout)
+	}
+}
+
+// BufferStats describes the buffer of an asynchronous handler.
+type BufferStats struct {
+	Sink       string
+	BufferSize int
+	Dropped    uint64
+}
+
+// bufferedHandler is implemented by handlers that buffer records before writing them.
+type bufferedHandler interface {
+	Sink() string
+	BufferSize() int
+	Dropped() uint64
+	Pending() int
+}
+
+// BufferStats returns buffer statistics of all buffered handlers.
+func (m *MultiHandler) BufferStats() []BufferStats {
+	var stats []BufferStats
+	for _, h := range m.handlers {
+		if buffered, ok := h.(bufferedHandler); ok {
+			stats = append(stats, BufferStats{
+				Sink:       buffered.Sink(),
+				BufferSize: buffered.BufferSize(),
+				Dropped:    buffered.Dropped(),
+			})
+		}
+	}
+	return stats
+}
+
+// Supported formats of the local stdout handler.
+const (
+	FormatConsole = "console" // colored human-readable lines (default)
+	FormatText    = "text"    // slog key=value text
+	FormatJSON    = "json"    // slog JSON
+)
+
+// NewLocalHandler returns the stdout handler for the given format.
+func NewLocalHandler(format string) (slog.Handler, error) {
+	switch format {
+	case "", FormatConsole:
+		return NewStdoutHandler(), nil
+	case FormatText:
+		return slog.NewTextHandler(os.Stdout, nil), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(os.Stdout, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", format)
 	}
 }
 
 // NewLogger initializes the combined logger with Kafka, File, and Stdout handlers.
-func NewLogger(brokers []string, kafkaTopic, serviceName string, bufferSize int) (*slog.Logger, error) {
+// The format selects the stdout handler and does not affect the Kafka sink.
+// If brokers is empty, Kafka is not used and records go to the stdout handler only.
+func NewLogger(brokers []string, kafkaTopic, serviceName string, bufferSize int, format string) (*slog.Logger, error) {
+	stdoutHandler, err := NewLocalHandler(format)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(brokers) == 0 {
+		return slog.New(NewMultiHandler(stdoutHandler)), nil
+	}
+
 	kafkaHandler, err := NewKafkaHandler(brokers, kafkaTopic, bufferSize)
 	if err != nil {
 		return nil, err
@@ -358,8 +518,6 @@ func NewLogger(brokers []string, kafkaTopic, serviceName string, bufferSize int)
 		return nil, err
 	}
 
-	stdoutHandler := NewStdoutHandler()
-
 	multiHandler := NewMultiHandler(kafkaHandler, fileHandler, stdoutHandler)
 
 	logger := slog.New(multiHandler)
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// ParseSQLLogLevel проверяет и возвращает уровень логирования SQL-запросов. Пустая строка означает silent
+func ParseSQLLogLevel(value string) (gormlogger.LogLevel, error) {
+	switch value {
+	case "", "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	default:
+		return 0, fmt.Errorf("unknown SQL log level %q: must be silent, error, warn or info", value)
+	}
+}
+
+// sqlLogger передает логи GORM в slog.
+// На уровне error логируются ошибочные запросы, на уровне warn — также запросы дольше slowThreshold,
+// на уровне info — все запросы
+type sqlLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// newSQLLogger создает новый экземпляр sqlLogger; slowThreshold 0 отключает логирование медленных запросов
+func newSQLLogger(logger *slog.Logger, level gormlogger.LogLevel, slowThreshold time.Duration) *sqlLogger {
+	return &sqlLogger{logger: logger, level: level, slowThreshold: slowThreshold}
+}
+
+// LogMode возвращает копию логгера с другим уровнем
+func (l *sqlLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// Info логирует информационное сообщение GORM
+func (l *sqlLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn логирует предупреждение GORM
+func (l *sqlLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error логирует ошибку GORM
+func (l *sqlLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace логирует выполненный запрос в зависимости от уровня, времени выполнения и ошибки.
+// Отсутствие записи не считается ошибкой запроса
+func (l *sqlLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "sql query failed",
+			slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed), slog.Any("error", err))
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "slow sql query",
+			slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed), slog.Duration("threshold", l.slowThreshold))
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.logger.InfoContext(ctx, "sql query", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed))
+	}
+}
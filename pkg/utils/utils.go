@@ -1,28 +1,60 @@
 package utils
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	pb "github.com/watchlist-kata/protos/subscription"
 	"log"
+	"log/slog"
 	"net"
+	"os"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"github.com/watchlist-kata/subscription/api/server"
 	"github.com/watchlist-kata/subscription/internal/config"
+	"github.com/watchlist-kata/subscription/internal/metrics"
+	"github.com/watchlist-kata/subscription/internal/repository"
 	"github.com/watchlist-kata/subscription/internal/service"
 )
 
-// SetupDatabase настраивает подключение к базе данных
-func SetupDatabase(cfg *config.Config) (*gorm.DB, error) {
+// healthUpdateInterval — период обновления состояния внешних сервисов в health-сервисе
+const healthUpdateInterval = 5 * time.Second
+
+// SetupDatabase настраивает подключение к базе данных.
+// Если задан DBStatementTimeout, Postgres прерывает запросы, выполняющиеся дольше этого времени.
+// Запросы логируются в logger с уровнем DBLogLevel (по умолчанию не логируются)
+func SetupDatabase(cfg *config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	sqlLogLevel, err := ParseSQLLogLevel(cfg.DBLogLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort,
 	)
+	if cfg.DBStatementTimeout > 0 {
+		// Неизвестные драйверу параметры DSN передаются серверу как параметры сессии
+		dsn += fmt.Sprintf(" statement_timeout=%d", cfg.DBStatementTimeout.Milliseconds())
+	}
+	if cfg.DBApplicationName != "" {
+		// Имя подключения позволяет отличить соединения сервиса в pg_stat_activity
+		dsn += " application_name=" + quoteDSNValue(cfg.DBApplicationName)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: newSQLLogger(logger, sqlLogLevel, cfg.DBSlowQueryThreshold),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -30,17 +62,60 @@ func SetupDatabase(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// StartGrpcServer запускает gRPC-сервер
-func StartGrpcServer(cfg *config.Config, subscriptionService service.SubscriptionService) error {
+// quoteDSNValue экранирует значение параметра DSN в формате key=value
+func quoteDSNValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// StartGrpcServer запускает gRPC-сервер и блокируется до его остановки.
+// После завершения ctx сервер перестает принимать новые вызовы и дожидается завершения текущих
+func StartGrpcServer(ctx context.Context, cfg *config.Config, subscriptionService service.SubscriptionService, healthReporter repository.HealthReporter) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf("%s", cfg.GRPCPort))
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	var opts []grpc.ServerOption
+	if cfg.GRPCTLSCertFile != "" {
+		creds, err := loadServerTLSCredentials(cfg)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else {
+		log.Printf("TLS is not configured, gRPC server is running without transport security")
+	}
+	var interceptors []grpc.UnaryServerInterceptor
+	if len(cfg.DisabledRPCs) > 0 {
+		gate, err := server.DisabledMethodsInterceptor(cfg.DisabledRPCs)
+		if err != nil {
+			return err
+		}
+		interceptors = append(interceptors, gate)
+		log.Printf("Disabled RPCs: %v", cfg.DisabledRPCs)
+	}
+	if cfg.MetricsAddr != "" {
+		interceptors = append(interceptors, metrics.UnaryServerInterceptor())
+	}
+	if cfg.GRPCCompression {
+		interceptors = append(interceptors, server.CompressionInterceptor())
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+
+	grpcServer := grpc.NewServer(opts...)
 	subscriptionServer := server.NewGrpcSubscriptionServer(subscriptionService)
 	pb.RegisterSubscriptionServiceServer(grpcServer, subscriptionServer)
 
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go watchDependencyHealth(healthServer, healthReporter)
+
+	go func() {
+		<-ctx.Done()
+		log.Printf("Stopping gRPC server...")
+		grpcServer.GracefulStop()
+	}()
+
 	log.Printf("Starting gRPC server on port %s...", cfg.GRPCPort)
 	if err := grpcServer.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
@@ -48,3 +123,49 @@ func StartGrpcServer(cfg *config.Config, subscriptionService service.Subscriptio
 
 	return nil
 }
+
+// loadServerTLSCredentials загружает сертификат сервера и, если задан CA клиентов, включает mTLS
+func loadServerTLSCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.GRPCTLSCertFile, cfg.GRPCTLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.GRPCTLSClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.GRPCTLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse client CA")
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// watchDependencyHealth периодически публикует состояние внешних сервисов в health-сервисе.
+// Общий статус ("") отражает работоспособность самого сервиса, статусы внешних сервисов
+// доступны по их именам (media, review, watchlist, user)
+func watchDependencyHealth(healthServer *health.Server, healthReporter repository.HealthReporter) {
+	ticker := time.NewTicker(healthUpdateInterval)
+	defer ticker.Stop()
+	for {
+		for name, healthy := range healthReporter.DependencyHealth() {
+			servingStatus := healthpb.HealthCheckResponse_SERVING
+			if !healthy {
+				servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			healthServer.SetServingStatus(name, servingStatus)
+		}
+		<-ticker.C
+	}
+}